@@ -72,23 +72,7 @@ func runGenenv(cmd *cobra.Command, args []string) error {
 	}
 
 	// Create .env content
-	// Note: Hash must be single-quoted to prevent $ being interpreted as variable reference
-	content := fmt.Sprintf(`# CoinOps Dashboard Authentication Credentials
-# Generated by: coinops genenv
-# DO NOT commit this file to version control!
-
-# Basic Authentication
-# Username is stored in plaintext (not sensitive)
-BASIC_AUTH_USERNAME=%s
-
-# Password is stored as a bcrypt hash (salted, one-way hash)
-# Original password cannot be recovered from this hash
-# Single quotes prevent $ from being interpreted as variable reference
-BASIC_AUTH_PASSWORD_HASH='%s'
-
-# This file is automatically loaded by 'coinops serve'
-# For Docker: docker run --env-file .env ...
-`, username, string(passwordHash))
+	content := envFileContent(username, string(passwordHash))
 
 	// Write to file with restricted permissions (0600 = rw-------)
 	if err := os.WriteFile(absPath, []byte(content), 0600); err != nil {
@@ -116,6 +100,29 @@ BASIC_AUTH_PASSWORD_HASH='%s'
 	return nil
 }
 
+// envFileContent builds the contents of a .env file for the given
+// credentials, shared by 'coinops genenv' and the first-run prompt in
+// 'coinops serve'. Note: the hash is single-quoted to prevent $ being
+// interpreted as a variable reference.
+func envFileContent(username, passwordHash string) string {
+	return fmt.Sprintf(`# CoinOps Dashboard Authentication Credentials
+# Generated by: coinops genenv
+# DO NOT commit this file to version control!
+
+# Basic Authentication
+# Username is stored in plaintext (not sensitive)
+BASIC_AUTH_USERNAME=%s
+
+# Password is stored as a bcrypt hash (salted, one-way hash)
+# Original password cannot be recovered from this hash
+# Single quotes prevent $ from being interpreted as variable reference
+BASIC_AUTH_PASSWORD_HASH='%s'
+
+# This file is automatically loaded by 'coinops serve'
+# For Docker: docker run --env-file .env ...
+`, username, passwordHash)
+}
+
 // generateSecureString creates a cryptographically secure random string
 // using base64url encoding (URL-safe, no padding)
 func generateSecureString(length int) (string, error) {
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
 	"text/tabwriter"
 	"time"
 
@@ -72,6 +73,9 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	// Create CoinGecko service with our coins
 	service := coingecko.NewService(cfg.Coins)
+	if cfg.Coingecko.VsCurrency != "" {
+		service.SetVsCurrency(cfg.Coingecko.VsCurrency)
+	}
 
 	// Fetch prices
 	fmt.Fprintf(os.Stderr, "Fetching prices for %d coins from CoinGecko...\n", len(cfg.Coins))
@@ -83,7 +87,7 @@ func runList(cmd *cobra.Command, args []string) error {
 
 	// Output as TSV
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tDISPLAY_NAME\tUSD\t24H_CHANGE")
+	fmt.Fprintf(w, "ID\tDISPLAY_NAME\t%s\t24H_CHANGE\n", strings.ToUpper(cfg.Coingecko.VsCurrency))
 
 	for _, coin := range coins {
 		fmt.Fprintf(w, "%s\t%s\t%.2f\t%.2f%%\n",
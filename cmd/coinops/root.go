@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -13,12 +15,54 @@ import (
 	"pahg-template/internal/config"
 )
 
+// supportedConfigExtensions are the file extensions initConfig recognizes
+// when --config points at an explicit file. viper.SetConfigFile already
+// detects the format from the extension internally, but only for a format
+// it can actually parse - checking against this set up front lets us return
+// a clear error for anything else instead of an opaque viper parse failure.
+var supportedConfigExtensions = map[string]bool{
+	"yaml": true,
+	"yml":  true,
+	"json": true,
+	"toml": true,
+}
+
+// isSupportedConfigExtension reports whether path's extension is one viper
+// can parse for us, so an explicit --config file gets a clear error instead
+// of an opaque failure out of viper.ReadInConfig.
+func isSupportedConfigExtension(path string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	return supportedConfigExtensions[ext]
+}
+
 var (
 	cfgFile    string
 	cfg        *config.Config
 	configUsed string
+	// debugMode gates initConfig's [DIAG] diagnostics: default runs (and
+	// piped output like `list`'s TSV) stay quiet, --debug or COINOPS_DEBUG
+	// turns them back on for troubleshooting a config-loading problem.
+	debugMode bool
+	// deferFatalConfigExit is set when `coinops validate` is the invoked
+	// command, so initConfig loads and unmarshals cfg as usual but leaves
+	// reporting an invalid config to validate's RunE - which builds a full
+	// per-check report - instead of dying on the first Validate() error.
+	deferFatalConfigExit bool
+	// strictEnvExpansion makes config.ExpandEnv error on a ${VAR} reference
+	// to an unset environment variable instead of expanding it to "".
+	// Settable via --strict-env or COINOPS_STRICT_ENV.
+	strictEnvExpansion bool
 )
 
+func init() {
+	for _, arg := range os.Args[1:] {
+		if arg == "validate" {
+			deferFatalConfigExit = true
+			break
+		}
+	}
+}
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "coinops",
@@ -41,47 +85,72 @@ func init() {
 
 	// Global flags
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./config.yaml)")
+	rootCmd.PersistentFlags().BoolVar(&debugMode, "debug", false, "print [DIAG] config-loading diagnostics to stderr (also settable via COINOPS_DEBUG)")
+	rootCmd.PersistentFlags().BoolVar(&strictEnvExpansion, "strict-env", false, "fail startup if a ${VAR} in the config references an unset environment variable (also settable via COINOPS_STRICT_ENV)")
+}
+
+// diagf prints a [DIAG] line to stderr when debug mode is on (--debug or
+// COINOPS_DEBUG), so default runs - and commands like `list` that pipe
+// their stdout into something else - stay quiet.
+func diagf(format string, args ...interface{}) {
+	if !debugMode {
+		return
+	}
+	fmt.Fprintf(os.Stderr, "[DIAG] "+format, args...)
 }
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	if !debugMode {
+		debugMode, _ = strconv.ParseBool(os.Getenv("COINOPS_DEBUG"))
+	}
+
 	defaults := config.DefaultConfig()
 
-	// DIAGNOSTIC: Log what config flag was passed
-	fmt.Fprintf(os.Stderr, "[DIAG] Config flag: %q\n", cfgFile)
-	fmt.Fprintf(os.Stderr, "[DIAG] CWD: %s\n", mustGetCwd())
+	diagf("Config flag: %q\n", cfgFile)
+	diagf("CWD: %s\n", mustGetCwd())
 
 	if cfgFile != "" {
-		// Use config file from the flag
+		// Use config file from the flag; viper.SetConfigFile detects the
+		// format from the extension itself, but only understands the ones
+		// below, so reject anything else with a clear error up front.
+		if !isSupportedConfigExtension(cfgFile) {
+			fmt.Fprintf(os.Stderr, "[FATAL] unrecognized config file extension %q on %s: supported extensions are yaml, yml, json, toml\n",
+				strings.TrimPrefix(filepath.Ext(cfgFile), "."), cfgFile)
+			os.Exit(1)
+		}
 		viper.SetConfigFile(cfgFile)
 
-		// DIAGNOSTIC: Check if the file actually exists
+		// Check if the file actually exists
 		if info, err := os.Stat(cfgFile); err != nil {
-			fmt.Fprintf(os.Stderr, "[DIAG] Config file stat ERROR: %v\n", err)
+			diagf("Config file stat ERROR: %v\n", err)
 		} else {
-			fmt.Fprintf(os.Stderr, "[DIAG] Config file exists: size=%d, mode=%s\n", info.Size(), info.Mode())
+			diagf("Config file exists: size=%d, mode=%s\n", info.Size(), info.Mode())
 
 			// Try to read first 200 bytes to verify content
 			if data, err := os.ReadFile(cfgFile); err != nil {
-				fmt.Fprintf(os.Stderr, "[DIAG] Config file read ERROR: %v\n", err)
+				diagf("Config file read ERROR: %v\n", err)
 			} else {
 				preview := string(data)
 				if len(preview) > 500 {
 					preview = preview[:500] + "..."
 				}
-				fmt.Fprintf(os.Stderr, "[DIAG] Config file preview:\n%s\n", preview)
+				diagf("Config file preview:\n%s\n", preview)
 
 				// Count coins in raw file
 				coinCount := strings.Count(string(data), "- id:")
-				fmt.Fprintf(os.Stderr, "[DIAG] Raw file contains %d coin entries\n", coinCount)
+				diagf("Raw file contains %d coin entries\n", coinCount)
 			}
 		}
 	} else {
-		// Search for config in current directory
+		// Search for config in current directory. Deliberately no
+		// SetConfigType call here: viper.SetConfigName + AddConfigPath
+		// without an explicit type searches its supported extensions in
+		// turn, so a bare "config.json" or "config.toml" is found instead
+		// of only ever looking for config.yaml.
 		viper.AddConfigPath(".")
-		viper.SetConfigType("yaml")
 		viper.SetConfigName("config")
-		fmt.Fprintf(os.Stderr, "[DIAG] No config flag, searching in CWD for config.yaml\n")
+		diagf("No config flag, searching in CWD for config.{yaml,yml,json,toml,...}\n")
 	}
 
 	// Environment variables
@@ -103,38 +172,55 @@ func initConfig() {
 	configUsed = "defaults-only"
 	if err := viper.ReadInConfig(); err == nil {
 		configUsed = viper.ConfigFileUsed()
-		fmt.Fprintf(os.Stderr, "[DIAG] Viper successfully read config: %s\n", configUsed)
+		diagf("Viper successfully read config: %s\n", configUsed)
 
 		// Check what viper thinks it has for coins
 		viperCoins := viper.Get("coins")
-		fmt.Fprintf(os.Stderr, "[DIAG] Viper coins type: %T\n", viperCoins)
+		diagf("Viper coins type: %T\n", viperCoins)
 		if coins, ok := viperCoins.([]interface{}); ok {
-			fmt.Fprintf(os.Stderr, "[DIAG] Viper has %d coins in memory\n", len(coins))
+			diagf("Viper has %d coins in memory\n", len(coins))
 		}
 	} else {
-		fmt.Fprintf(os.Stderr, "[DIAG] Viper ReadInConfig ERROR: %v\n", err)
+		diagf("Viper ReadInConfig ERROR: %v\n", err)
 		configUsed = fmt.Sprintf("defaults-only (error: %v)", err)
 	}
 
 	// Unmarshal into a FRESH config struct (not pre-populated)
 	cfg = &config.Config{}
 	if err := viper.Unmarshal(cfg); err != nil {
-		fmt.Fprintf(os.Stderr, "[DIAG] Viper Unmarshal ERROR: %v\n", err)
+		diagf("Viper Unmarshal ERROR: %v\n", err)
 		cfg = defaults // fallback
 	}
 
-	// Validate mandatory configuration fields
-	if err := cfg.Validate(); err != nil {
+	if !strictEnvExpansion {
+		strictEnvExpansion, _ = strconv.ParseBool(os.Getenv("COINOPS_STRICT_ENV"))
+	}
+
+	// Expand ${VAR} references (e.g. a per-region CoinGecko catalog cache
+	// path or coin display name) against the environment, so multi-region deploys don't
+	// need to template the YAML externally.
+	if err := config.ExpandEnv(cfg, strictEnvExpansion); err != nil {
+		diagf("Config env expansion ERROR: %v\n", err)
+		if !deferFatalConfigExit {
+			fmt.Fprintf(os.Stderr, "[FATAL] %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Validate mandatory configuration fields. `coinops validate` wants a
+	// full per-check report rather than dying on the first error, so it
+	// re-runs Validate() itself and handles reporting/exit status there.
+	if err := cfg.Validate(); err != nil && !deferFatalConfigExit {
 		fmt.Fprintf(os.Stderr, "[FATAL] Configuration validation failed: %v\n", err)
 		os.Exit(1)
 	}
 
-	fmt.Fprintf(os.Stderr, "[DIAG] Final config has %d coins\n", len(cfg.Coins))
+	diagf("Final config has %d coins\n", len(cfg.Coins))
 	if len(cfg.Coins) > 0 {
-		fmt.Fprintf(os.Stderr, "[DIAG] First coin: %s (%s)\n", cfg.Coins[0].ID, cfg.Coins[0].DisplayName)
+		diagf("First coin: %s (%s)\n", cfg.Coins[0].ID, cfg.Coins[0].DisplayName)
 	}
 	if len(cfg.Coins) > 5 {
-		fmt.Fprintf(os.Stderr, "[DIAG] Sixth coin: %s (%s)\n", cfg.Coins[5].ID, cfg.Coins[5].DisplayName)
+		diagf("Sixth coin: %s (%s)\n", cfg.Coins[5].ID, cfg.Coins[5].DisplayName)
 	}
 }
 
@@ -143,6 +229,35 @@ func GetConfig() *config.Config {
 	return cfg
 }
 
+// ReloadConfig re-reads and re-validates the config from the same source
+// initConfig originally loaded (config file + environment), for serve's
+// SIGHUP handler. Unlike initConfig, it never calls os.Exit: a bad reload
+// should be logged and leave the server running on its old config, not
+// kill the process. On success it also updates the package-level cfg so a
+// later GetConfig() call reflects the reload; on failure it leaves cfg
+// untouched and returns the error.
+func ReloadConfig() (*config.Config, error) {
+	if err := viper.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("failed to re-read config: %w", err)
+	}
+
+	newCfg := &config.Config{}
+	if err := viper.Unmarshal(newCfg); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	if err := config.ExpandEnv(newCfg, strictEnvExpansion); err != nil {
+		return nil, fmt.Errorf("config env expansion failed: %w", err)
+	}
+
+	if err := newCfg.Validate(); err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	cfg = newCfg
+	return cfg, nil
+}
+
 // GetConfigSource returns where the config was loaded from
 func GetConfigSource() string {
 	return configUsed
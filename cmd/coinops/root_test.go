@@ -0,0 +1,71 @@
+package main
+
+import (
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// captureStderr redirects os.Stderr for the duration of fn and returns
+// everything written to it.
+func captureStderr(t *testing.T, fn func()) string {
+	t.Helper()
+
+	original := os.Stderr
+	r, w, err := os.Pipe()
+	assert.NoError(t, err)
+	os.Stderr = w
+	defer func() { os.Stderr = original }()
+
+	fn()
+
+	assert.NoError(t, w.Close())
+	out, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(out)
+}
+
+func TestDiagf_SilentByDefault(t *testing.T) {
+	debugMode = false
+
+	out := captureStderr(t, func() {
+		diagf("this should not print\n")
+	})
+
+	assert.Empty(t, out)
+}
+
+func TestDiagf_PrintsWhenDebugModeEnabled(t *testing.T) {
+	debugMode = true
+	defer func() { debugMode = false }()
+
+	out := captureStderr(t, func() {
+		diagf("hello %s\n", "world")
+	})
+
+	assert.Equal(t, "[DIAG] hello world\n", out)
+}
+
+func TestIsSupportedConfigExtension(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		path string
+		want bool
+	}{
+		{"yaml", "config.yaml", true},
+		{"yml", "config.yml", true},
+		{"json", "config.json", true},
+		{"toml", "config.toml", true},
+		{"case insensitive", "config.YAML", true},
+		{"with directory", "/etc/coinops/config.toml", true},
+		{"unrecognized extension", "config.ini", false},
+		{"unrecognized extension conf", "config.conf", false},
+		{"no extension", "config", false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, isSupportedConfigExtension(tc.path))
+		})
+	}
+}
@@ -1,12 +1,14 @@
 package main
 
 import (
+	"bufio"
 	"context"
 	"fmt"
 	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
@@ -74,18 +76,64 @@ func runServe(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create server: %w", err)
 	}
+	srv.SetConfigSource(GetConfigSource())
+
+	// Catch a misconfigured coingecko.vs_currency (e.g. a typo like "usdd")
+	// before serving, since CoinGecko silently omits price fields for an
+	// unsupported currency and the ticker would otherwise just show zeros.
+	validateCtx, validateCancel := context.WithTimeout(context.Background(), 10*time.Second)
+	err = srv.ValidateVsCurrency(validateCtx)
+	validateCancel()
+	if err != nil {
+		if cfg.Coingecko.StrictVsCurrency {
+			return fmt.Errorf("coingecko.vs_currency validation failed: %w", err)
+		}
+		slog.Warn("vs_currency_unsupported", "vs_currency", cfg.Coingecko.VsCurrency, "error", err)
+	}
 
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
 
 	// Create HTTP server with explicit struct for graceful shutdown
+	//
+	// This always listens plain-HTTP: cfg.Security.ForceHTTPS only redirects
+	// clients to an HTTPS URL, it doesn't terminate TLS here. Deployments
+	// expect a reverse proxy in front (see cfg.Security.TrustedProxies,
+	// TrustForwardedChain) to do that, the same way they terminate it before
+	// forwarding X-Forwarded-Proto/-For. Native TLS with a hot-reloadable
+	// certificate (e.g. for zero-downtime Let's Encrypt renewal) would need
+	// that reverse-proxy assumption revisited first, so it isn't implemented.
 	httpServer := &http.Server{
-		Addr:    addr,
-		Handler: srv.Handler(),
+		Addr:              addr,
+		Handler:           srv.Handler(),
+		ReadTimeout:       time.Duration(cfg.Server.Timeouts.ReadTimeoutMs) * time.Millisecond,
+		ReadHeaderTimeout: time.Duration(cfg.Server.Timeouts.ReadHeaderTimeoutMs) * time.Millisecond,
+		WriteTimeout:      time.Duration(cfg.Server.Timeouts.WriteTimeoutMs) * time.Millisecond,
+		IdleTimeout:       time.Duration(cfg.Server.Timeouts.IdleTimeoutMs) * time.Millisecond,
 	}
 
-	// Channel to receive shutdown signals
-	shutdownChan := make(chan os.Signal, 1)
-	signal.Notify(shutdownChan, syscall.SIGTERM, syscall.SIGINT)
+	// Cancel on SIGINT/SIGTERM so Kubernetes rolling deploys drain in-flight
+	// requests instead of dropping them.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Reload config on SIGHUP instead of requiring a restart, so changing
+	// the coin list or a refresh interval doesn't drop in-flight requests.
+	// A config that fails validation is logged and discarded, leaving the
+	// server on its last-good config.
+	reloadChan := make(chan os.Signal, 1)
+	signal.Notify(reloadChan, syscall.SIGHUP)
+	defer signal.Stop(reloadChan)
+	go func() {
+		for range reloadChan {
+			newCfg, err := ReloadConfig()
+			if err != nil {
+				slog.Error("config_reload_failed", "error", err)
+				continue
+			}
+			diff := srv.ReloadConfig(newCfg)
+			slog.Info("config_reloaded", "changed", diff)
+		}
+	}()
 
 	// Channel to receive server errors
 	serverErrChan := make(chan error, 1)
@@ -103,20 +151,24 @@ func runServe(cmd *cobra.Command, args []string) error {
 
 	// Wait for shutdown signal or server error
 	select {
-	case sig := <-shutdownChan:
-		slog.Info("shutdown_signal_received", "signal", sig.String())
+	case <-ctx.Done():
+		slog.Info("shutdown_signal_received")
 	case err := <-serverErrChan:
 		return fmt.Errorf("server failed: %w", err)
 	}
 
 	// Graceful shutdown with timeout
-	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
 	defer cancel()
 
-	slog.Info("graceful_shutdown_starting", "timeout", shutdownTimeout.String())
+	drainStart := time.Now()
+
+	// Tell any open SSE streams to reconnect elsewhere before Shutdown waits
+	// on them, so they don't hold the shutdown open until shutdownTimeout.
+	srv.Drain()
 
 	// Shutdown HTTP server (waits for in-flight requests)
-	if err := httpServer.Shutdown(ctx); err != nil {
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
 		slog.Error("http_shutdown_error", "error", err)
 	}
 
@@ -125,7 +177,7 @@ func runServe(cmd *cobra.Command, args []string) error {
 		slog.Error("server_close_error", "error", err)
 	}
 
-	slog.Info("graceful_shutdown_complete")
+	slog.Info("server_shutdown", "drain_duration", time.Since(drainStart).String())
 	return nil
 }
 
@@ -161,6 +213,22 @@ func ensureCredentials() error {
 	os.Setenv("BASIC_AUTH_USERNAME", newUsername)
 	os.Setenv("BASIC_AUTH_PASSWORD_HASH", string(newHash))
 
+	// On an interactive terminal, offer to persist these credentials to a
+	// .env file so they survive a restart. Non-interactive runs (Docker,
+	// systemd, CI) keep the existing ephemeral behavior since there's no
+	// one to answer the prompt.
+	persisted := false
+	if isInteractive() {
+		if promptYesNo("No .env file found. Save these credentials to .env so they persist across restarts? [y/N]: ") {
+			content := envFileContent(newUsername, string(newHash))
+			if err := os.WriteFile(".env", []byte(content), 0600); err != nil {
+				fmt.Fprintf(os.Stderr, "[WARN] Failed to write .env file: %v\n", err)
+			} else {
+				persisted = true
+			}
+		}
+	}
+
 	// Print credentials prominently
 	fmt.Fprintln(os.Stderr, "")
 	fmt.Fprintln(os.Stderr, "=================================================================")
@@ -169,14 +237,40 @@ func ensureCredentials() error {
 	fmt.Fprintf(os.Stderr, "  Username: %s\n", newUsername)
 	fmt.Fprintf(os.Stderr, "  Password: %s\n", newPassword)
 	fmt.Fprintln(os.Stderr, "=================================================================")
-	fmt.Fprintln(os.Stderr, "  These credentials are valid for THIS SESSION ONLY.")
-	fmt.Fprintln(os.Stderr, "  For persistent credentials, run: coinops genenv")
-	fmt.Fprintln(os.Stderr, "  Or pass via: docker run -e BASIC_AUTH_USERNAME=... -e BASIC_AUTH_PASSWORD_HASH=...")
+	if persisted {
+		fmt.Fprintln(os.Stderr, "  Saved to .env - these credentials will persist across restarts.")
+	} else {
+		fmt.Fprintln(os.Stderr, "  These credentials are valid for THIS SESSION ONLY.")
+		fmt.Fprintln(os.Stderr, "  For persistent credentials, run: coinops genenv")
+		fmt.Fprintln(os.Stderr, "  Or pass via: docker run -e BASIC_AUTH_USERNAME=... -e BASIC_AUTH_PASSWORD_HASH=...")
+	}
 	fmt.Fprintln(os.Stderr, "=================================================================")
 	fmt.Fprintln(os.Stderr, "")
 
-	slog.Info("credentials_generated", "username", newUsername)
+	slog.Info("credentials_generated", "username", newUsername, "persisted", persisted)
 	return nil
 }
 
+// isInteractive reports whether stdin is attached to a terminal, so
+// ensureCredentials only prompts when there's a human present to answer.
+func isInteractive() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice != 0
+}
+
+// promptYesNo prints question to stdout and reads a line from stdin,
+// defaulting to no on EOF or unrecognized input.
+func promptYesNo(question string) bool {
+	fmt.Fprint(os.Stdout, question)
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+	answer := strings.ToLower(strings.TrimSpace(line))
+	return answer == "y" || answer == "yes"
+}
+
 // Note: generateSecureString is defined in genenv.go and shared across the package
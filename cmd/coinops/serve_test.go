@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// withNonTTYStdin points os.Stdin at a regular file for the duration of fn,
+// so isInteractive() reliably returns false regardless of how the test
+// binary itself was invoked.
+func withNonTTYStdin(t *testing.T, fn func()) {
+	t.Helper()
+
+	f, err := os.CreateTemp(t.TempDir(), "stdin")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	original := os.Stdin
+	os.Stdin = f
+	defer func() { os.Stdin = original }()
+
+	fn()
+}
+
+func TestIsInteractive_FalseWhenStdinIsNotATerminal(t *testing.T) {
+	withNonTTYStdin(t, func() {
+		assert.False(t, isInteractive())
+	})
+}
+
+func TestEnsureCredentials_NonInteractiveStaysEphemeral(t *testing.T) {
+	dir := t.TempDir()
+	original, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(original)
+
+	os.Unsetenv("BASIC_AUTH_USERNAME")
+	os.Unsetenv("BASIC_AUTH_PASSWORD_HASH")
+	defer func() {
+		os.Unsetenv("BASIC_AUTH_USERNAME")
+		os.Unsetenv("BASIC_AUTH_PASSWORD_HASH")
+	}()
+
+	withNonTTYStdin(t, func() {
+		assert.NoError(t, ensureCredentials())
+	})
+
+	assert.NotEmpty(t, os.Getenv("BASIC_AUTH_USERNAME"))
+	assert.NotEmpty(t, os.Getenv("BASIC_AUTH_PASSWORD_HASH"))
+
+	_, err = os.Stat(filepath.Join(dir, ".env"))
+	assert.True(t, os.IsNotExist(err), "non-interactive run should not write a .env file")
+}
+
+func TestEnvFileContent_ContainsUsernameAndHash(t *testing.T) {
+	content := envFileContent("myuser", "myhash")
+
+	assert.Contains(t, content, "BASIC_AUTH_USERNAME=myuser")
+	assert.Contains(t, content, "BASIC_AUTH_PASSWORD_HASH='myhash'")
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInvalidCIDRs_ReportsOnlyBadEntries(t *testing.T) {
+	errs := invalidCIDRs("security.ip_allowlist.cidrs", []string{"10.0.0.0/8", "not-a-cidr", "192.168.1.0/24"})
+
+	assert.Len(t, errs, 1)
+	assert.Contains(t, errs[0], "security.ip_allowlist.cidrs")
+	assert.Contains(t, errs[0], "not-a-cidr")
+}
+
+func TestInvalidCIDRs_AllValidReturnsNoErrors(t *testing.T) {
+	errs := invalidCIDRs("security.trusted_proxies", []string{"10.0.0.0/8", "::1/128"})
+
+	assert.Empty(t, errs)
+}
+
+func TestInvalidCIDRs_EmptyListReturnsNoErrors(t *testing.T) {
+	assert.Empty(t, invalidCIDRs("security.ip_allowlist.cidrs", nil))
+}
@@ -0,0 +1,79 @@
+// Package announcement provides a thread-safe in-memory store for a single
+// operator-set banner (e.g. "Maintenance tonight 10pm"), shown on the
+// dashboard until it's cleared or its optional expiry passes.
+package announcement
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// Severity values understood by layout.html's banner styling.
+const (
+	SeverityInfo     = "info"
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// Announcement is the operator-set banner text and how it should be styled.
+type Announcement struct {
+	Text     string
+	Severity string
+	// ExpiresAt is the time the announcement stops being shown. Zero means
+	// it never expires on its own and must be cleared explicitly.
+	ExpiresAt time.Time
+}
+
+// Store holds at most one active announcement in memory.
+type Store struct {
+	mu    sync.RWMutex
+	clock clockwork.Clock
+	cur   *Announcement
+}
+
+// NewStore creates an empty announcement store using the real clock.
+func NewStore() *Store {
+	return NewStoreWithClock(clockwork.NewRealClock())
+}
+
+// NewStoreWithClock creates an empty announcement store with a custom clock
+// (for testing expiry deterministically).
+func NewStoreWithClock(clock clockwork.Clock) *Store {
+	return &Store{clock: clock}
+}
+
+// Set replaces the current announcement. A zero expiresAt means it never
+// expires on its own.
+func (s *Store) Set(text, severity string, expiresAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur = &Announcement{Text: text, Severity: severity, ExpiresAt: expiresAt}
+}
+
+// Clear removes the current announcement, if any.
+func (s *Store) Clear() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cur = nil
+}
+
+// Get returns the current announcement, or nil if none is set or the one
+// that was set has expired.
+func (s *Store) Get() *Announcement {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.cur == nil {
+		return nil
+	}
+	if !s.cur.ExpiresAt.IsZero() && !s.clock.Now().Before(s.cur.ExpiresAt) {
+		return nil
+	}
+
+	a := *s.cur
+	return &a
+}
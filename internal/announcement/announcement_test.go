@@ -0,0 +1,51 @@
+package announcement
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStore_EmptyByDefault(t *testing.T) {
+	store := NewStore()
+
+	assert.Nil(t, store.Get())
+}
+
+func TestStore_SetAndGet(t *testing.T) {
+	store := NewStore()
+
+	store.Set("Maintenance tonight 10pm", SeverityWarning, time.Time{})
+
+	a := store.Get()
+	require.NotNil(t, a)
+	assert.Equal(t, "Maintenance tonight 10pm", a.Text)
+	assert.Equal(t, SeverityWarning, a.Severity)
+	assert.True(t, a.ExpiresAt.IsZero())
+}
+
+func TestStore_Clear(t *testing.T) {
+	store := NewStore()
+	store.Set("Maintenance tonight 10pm", SeverityInfo, time.Time{})
+
+	store.Clear()
+
+	assert.Nil(t, store.Get())
+}
+
+func TestStore_AutoExpires(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(clock)
+
+	store.Set("Maintenance tonight 10pm", SeverityCritical, clock.Now().Add(time.Hour))
+	require.NotNil(t, store.Get())
+
+	clock.Advance(59 * time.Minute)
+	assert.NotNil(t, store.Get())
+
+	clock.Advance(2 * time.Minute)
+	assert.Nil(t, store.Get())
+}
@@ -0,0 +1,151 @@
+package audit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewStore(t *testing.T) {
+	store := NewStore()
+
+	require.NotNil(t, store)
+	assert.Equal(t, 0, store.Count())
+	assert.Empty(t, store.GetAll())
+}
+
+func TestStore_Add(t *testing.T) {
+	store := NewStore()
+
+	e := store.Add("admin", "sessions.revoke", "username=alice")
+
+	assert.Equal(t, 1, e.ID)
+	assert.Equal(t, "admin", e.Actor)
+	assert.Equal(t, "sessions.revoke", e.Action)
+	assert.Equal(t, "username=alice", e.Detail)
+	assert.False(t, e.Timestamp.IsZero())
+	assert.Equal(t, 1, store.Count())
+}
+
+func TestStore_Add_AutoIncrementID(t *testing.T) {
+	store := NewStore()
+
+	e1 := store.Add("admin", "a", "")
+	e2 := store.Add("admin", "b", "")
+	e3 := store.Add("admin", "c", "")
+
+	assert.Equal(t, 1, e1.ID)
+	assert.Equal(t, 2, e2.ID)
+	assert.Equal(t, 3, e3.ID)
+}
+
+func TestStore_GetAll_OldestFirst(t *testing.T) {
+	store := NewStore()
+
+	store.Add("admin", "first", "")
+	store.Add("admin", "second", "")
+	store.Add("admin", "third", "")
+
+	all := store.GetAll()
+
+	require.Len(t, all, 3)
+	assert.Equal(t, "first", all[0].Action)
+	assert.Equal(t, "third", all[2].Action)
+}
+
+func TestStore_GetAll_ReturnsCopy(t *testing.T) {
+	store := NewStore()
+	store.Add("admin", "original", "")
+
+	all1 := store.GetAll()
+	all2 := store.GetAll()
+
+	all1[0].Action = "modified"
+	assert.Equal(t, "original", all2[0].Action)
+}
+
+func TestStore_Count(t *testing.T) {
+	store := NewStore()
+	assert.Equal(t, 0, store.Count())
+
+	store.Add("admin", "a", "")
+	assert.Equal(t, 1, store.Count())
+}
+
+func TestStore_Clear(t *testing.T) {
+	store := NewStore()
+	store.Add("admin", "a", "")
+	store.Add("admin", "b", "")
+
+	store.Clear()
+
+	assert.Equal(t, 0, store.Count())
+	assert.Empty(t, store.GetAll())
+}
+
+func TestStore_GetRange_FiltersByTimestamp(t *testing.T) {
+	store := NewStore()
+
+	store.entries = []Entry{
+		{ID: 1, Timestamp: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), Action: "a"},
+		{ID: 2, Timestamp: time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), Action: "b"},
+		{ID: 3, Timestamp: time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC), Action: "c"},
+	}
+	store.nextID = 4
+
+	from := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 1, 9, 0, 0, 0, 0, time.UTC)
+
+	result := store.GetRange(from, to)
+
+	require.Len(t, result, 1)
+	assert.Equal(t, "b", result[0].Action)
+}
+
+func TestStore_GetRange_UnboundedWhenZero(t *testing.T) {
+	store := NewStore()
+	store.Add("admin", "a", "")
+	store.Add("admin", "b", "")
+
+	result := store.GetRange(time.Time{}, time.Time{})
+
+	assert.Len(t, result, 2)
+}
+
+func TestEntry_Redacted_MasksSecretLikeDetail(t *testing.T) {
+	e := Entry{Detail: "reset password=hunter2 for user"}
+
+	redacted := e.Redacted()
+
+	assert.NotContains(t, redacted.Detail, "hunter2")
+	assert.Contains(t, redacted.Detail, "password=[REDACTED]")
+}
+
+func TestEntry_Redacted_LeavesOrdinaryDetailUntouched(t *testing.T) {
+	e := Entry{Detail: "username=alice count=3"}
+
+	redacted := e.Redacted()
+
+	assert.Equal(t, "username=alice count=3", redacted.Detail)
+}
+
+func TestStore_ThreadSafety_ConcurrentAdd(t *testing.T) {
+	store := NewStore()
+
+	var wg sync.WaitGroup
+	numGoroutines := 100
+
+	wg.Add(numGoroutines)
+	for i := 0; i < numGoroutines; i++ {
+		go func() {
+			defer wg.Done()
+			store.Add("admin", "action", "")
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, numGoroutines, store.Count())
+}
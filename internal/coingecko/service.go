@@ -1,51 +1,513 @@
 package coingecko
 
 import (
+	"container/list"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"math/rand"
 	"net/http"
+	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
+	"github.com/jonboulle/clockwork"
+
 	"pahg-template/internal/config"
 )
 
+// defaultBaseURL is the public CoinGecko API root. SetBaseURL overrides it,
+// e.g. to point at an httptest.NewServer in tests or an internal caching
+// proxy in enterprise deployments.
+const defaultBaseURL = "https://api.coingecko.com/api/v3"
+
+// proBaseURL is used instead of defaultBaseURL when COINGECKO_API_KEY is
+// set, since CoinGecko's Pro tier is served from a separate host.
+const proBaseURL = "https://pro-api.coingecko.com/api/v3"
+
+// apiKeyHeader is the header CoinGecko's Pro tier expects the API key on.
+const apiKeyHeader = "x-cg-pro-api-key"
+
+// defaultPingURL is CoinGecko's lightweight liveness endpoint.
+const defaultPingURL = defaultBaseURL + "/ping"
+
+// defaultCatalogURL lists every coin CoinGecko tracks, including symbols.
+// It's large and rarely changes, so it gets a much longer TTL than prices.
+const defaultCatalogURL = defaultBaseURL + "/coins/list"
+
+// defaultPriceURL is CoinGecko's simple/price endpoint used by GetPrices.
+const defaultPriceURL = defaultBaseURL + "/simple/price"
+
+// catalogCacheTTL controls how long the coins/list catalog is cached before
+// a fresh fetch is attempted.
+const catalogCacheTTL = 24 * time.Hour
+
+// defaultMarketChartURL is CoinGecko's per-coin historical price endpoint,
+// used by GetHistory. %s is the coin ID.
+const defaultMarketChartURL = defaultBaseURL + "/coins/%s/market_chart"
+
+// historyCacheTTL controls how long a coin's price history is cached before
+// a fresh fetch is attempted. History changes far more slowly than spot
+// price, so it gets a much longer TTL than the price cache.
+const historyCacheTTL = 1 * time.Hour
+
+// unavailableRecheckInterval bounds how long a coin ID stays excluded from
+// price fetches after being marked unavailable (see markUnavailable),
+// before doFetchPrices requests it again.
+const unavailableRecheckInterval = 1 * time.Hour
+
+// defaultHistoryCacheSize caps how many (coin, days) history entries
+// GetHistory keeps at once when SetHistoryCacheSize hasn't overridden it.
+// Only the last-viewed few coin detail pages need to stay warm, so this
+// bounds memory instead of letting historyCache grow with every distinct
+// coin/days combination ever requested.
+const defaultHistoryCacheSize = 20
+
+// PricePoint is a single timestamped price sample, used to render sparkline
+// charts of a coin's recent history.
+type PricePoint struct {
+	Timestamp time.Time `json:"timestamp"`
+	Price     float64   `json:"price"`
+}
+
+// historyCacheEntry is one coin/days combination's cached history.
+type historyCacheEntry struct {
+	key       string
+	points    []PricePoint
+	fetchedAt time.Time
+}
+
+// historyLRU is a fixed-size, TTL-expiring cache of historyCacheEntry
+// keyed by historyCacheKey's (coinID, days) string, backing GetHistory.
+// Least-recently-used entries are evicted once size is exceeded, and an
+// entry older than ttl is treated as a miss and refetched - bounding
+// memory while still speeding up repeat coin detail views. Safe for
+// concurrent use.
+type historyLRU struct {
+	mu    sync.Mutex
+	clock clockwork.Clock
+	size  int
+	ttl   time.Duration
+	ll    *list.List
+	items map[string]*list.Element
+}
+
+// newHistoryLRU creates a historyLRU holding at most size entries, each
+// valid for ttl after being set.
+func newHistoryLRU(size int, ttl time.Duration, clock clockwork.Clock) *historyLRU {
+	return &historyLRU{
+		clock: clock,
+		size:  size,
+		ttl:   ttl,
+		ll:    list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// get returns key's cached points and true if present and not yet expired,
+// moving it to the front of the recency list. An expired entry is evicted
+// immediately rather than left for the next overflow to clear out.
+func (c *historyLRU) get(key string) ([]PricePoint, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*historyCacheEntry)
+	if c.clock.Now().Sub(entry.fetchedAt) >= c.ttl {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	points := make([]PricePoint, len(entry.points))
+	copy(points, entry.points)
+	return points, true
+}
+
+// set caches points under key, evicting the least-recently-used entry if
+// this insert would grow the cache past its size.
+func (c *historyLRU) set(key string, points []PricePoint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if el, ok := c.items[key]; ok {
+		entry := el.Value.(*historyCacheEntry)
+		entry.points = points
+		entry.fetchedAt = c.clock.Now()
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(&historyCacheEntry{key: key, points: points, fetchedAt: c.clock.Now()})
+	c.items[key] = el
+
+	if c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		if oldest != nil {
+			c.ll.Remove(oldest)
+			delete(c.items, oldest.Value.(*historyCacheEntry).key)
+		}
+	}
+}
+
+// setSize changes the cache's capacity, trimming the least-recently-used
+// entries immediately if it shrinks below the current entry count.
+func (c *historyLRU) setSize(size int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.size = size
+	for c.ll.Len() > c.size {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*historyCacheEntry).key)
+	}
+}
+
+// CatalogEntry is a single coin from CoinGecko's full coins/list catalog.
+type CatalogEntry struct {
+	ID     string `json:"id"`
+	Symbol string `json:"symbol"`
+	Name   string `json:"name"`
+}
+
+// defaultVsCurrency is used when config doesn't specify one.
+const defaultVsCurrency = "usd"
+
+// defaultCacheTTL is used when config doesn't specify a price cache TTL, or
+// specifies a zero/negative one.
+const defaultCacheTTL = 30 * time.Second
+
+// defaultRetryAttempts and defaultRetryBaseDelay are used when config
+// doesn't specify retry settings for the price fetch, or specifies
+// zero/negative ones.
+const (
+	defaultRetryAttempts  = 3
+	defaultRetryBaseDelay = 200 * time.Millisecond
+)
+
+// defaultClientTimeout is the price fetch's overall retry budget when
+// s.client.Timeout is unset (e.g. a test swaps in a client with no
+// timeout), matching the timeout NewServiceWithClock's default client uses.
+const defaultClientTimeout = 10 * time.Second
+
 // Coin represents a cryptocurrency with its price data
 type Coin struct {
 	ID          string
 	DisplayName string
 	Price       float64
 	Change24h   float64
+	// Currency is the ISO-4217-ish code (e.g. "usd", "eur") Price and
+	// Change24h are denominated in, so templates can render the right symbol.
+	Currency string
 }
 
 // Service fetches cryptocurrency prices from CoinGecko
 type Service struct {
-	client    *http.Client
+	client *http.Client
+	// coinsMu guards coins, which is replaced wholesale (not mutated in
+	// place) by SetCoins when a config reload changes the tracked coin
+	// list, so readers always see either the old list or the new one.
+	coinsMu   sync.RWMutex
 	coins     []config.CoinConfig
 	cache     []Coin
 	cacheMu   sync.RWMutex
 	cacheTime time.Time
 	cacheTTL  time.Duration
+	// refreshMu serializes forced upstream fetches (fetchAndCachePrices) so
+	// concurrent RefreshCoin/cache-miss callers coalesce into one request
+	// instead of stampeding CoinGecko.
+	refreshMu  sync.Mutex
+	pingURL    string
+	priceURL   string
+	vsCurrency string
+	clock      clockwork.Clock
+
+	// apiKey is CoinGecko's Pro API key, attached as the x-cg-pro-api-key
+	// header on every request when set. Empty means the public, more
+	// heavily rate-limited API is used.
+	apiKey string
+
+	// retryAttempts and retryBaseDelay control doFetchPrices' exponential
+	// backoff retry of the CoinGecko price fetch.
+	retryAttempts  int
+	retryBaseDelay time.Duration
+
+	// rateLimitMu guards rateLimitedUntil, which is set when CoinGecko
+	// returns 429 so doFetchPrices skips upstream fetches (serving
+	// cached/fallback data instead) until the Retry-After deadline passes,
+	// rather than risking a temporary API key ban by hammering it further.
+	rateLimitMu      sync.RWMutex
+	rateLimitedUntil time.Time
+
+	// generation increments every time a real fetch produces a new price
+	// snapshot, so clients can ask "what changed since generation N".
+	generation int64
+	// changedAt records the generation a coin's price last changed at.
+	changedAt map[string]int64
+
+	catalogURL       string
+	catalogCachePath string // optional; empty disables disk persistence
+	catalogMu        sync.RWMutex
+	catalogCache     []CatalogEntry
+	catalogCacheTime time.Time
+
+	supportedVsCurrenciesURL  string
+	supportedVsCurrenciesMu   sync.RWMutex
+	supportedVsCurrenciesTime time.Time
+	supportedVsCurrencies     map[string]bool
+
+	marketChartURL string
+	historyCache   *historyLRU
+
+	// unavailableMu guards unavailableCoins, which maps a coin ID CoinGecko
+	// didn't recognize to when it was marked so. Unlike rateLimitedUntil (a
+	// 429, backed off for a server-given Retry-After), an unrecognized ID
+	// gets a fixed unavailableRecheckInterval before it's requested again -
+	// long enough that repeatedly requesting a genuinely bad ID doesn't
+	// waste request slots, but bounded so a coin CoinGecko lists tomorrow
+	// (or one only temporarily missing from a response) isn't dropped
+	// forever.
+	unavailableMu    sync.RWMutex
+	unavailableCoins map[string]time.Time
 }
 
 // NewService creates a new CoinGecko service instance
 func NewService(coins []config.CoinConfig) *Service {
+	return NewServiceWithClock(coins, clockwork.NewRealClock())
+}
+
+// NewServiceWithClock creates a new CoinGecko service instance using a
+// custom clock, primarily so cache TTL behavior can be tested deterministically.
+// If COINGECKO_API_KEY is set, requests use CoinGecko's Pro base URL and
+// carry the key on the x-cg-pro-api-key header instead of hitting the more
+// heavily rate-limited public API.
+func NewServiceWithClock(coins []config.CoinConfig, clock clockwork.Clock) *Service {
+	baseURL := defaultBaseURL
+	apiKey := os.Getenv("COINGECKO_API_KEY")
+	if apiKey != "" {
+		baseURL = proBaseURL
+	}
+
 	return &Service{
 		client: &http.Client{
-			Timeout: 10 * time.Second,
+			Timeout: defaultClientTimeout,
 		},
-		coins:    coins,
-		cacheTTL: 30 * time.Second,
+		coins:                    coins,
+		cacheTTL:                 defaultCacheTTL,
+		retryAttempts:            defaultRetryAttempts,
+		retryBaseDelay:           defaultRetryBaseDelay,
+		pingURL:                  baseURL + "/ping",
+		priceURL:                 baseURL + "/simple/price",
+		vsCurrency:               defaultVsCurrency,
+		catalogURL:               baseURL + "/coins/list",
+		marketChartURL:           baseURL + "/coins/%s/market_chart",
+		supportedVsCurrenciesURL: baseURL + "/simple/supported_vs_currencies",
+		apiKey:                   apiKey,
+		clock:                    clock,
+		changedAt:                make(map[string]int64),
+		historyCache:             newHistoryLRU(defaultHistoryCacheSize, historyCacheTTL, clock),
+		unavailableCoins:         make(map[string]time.Time),
+	}
+}
+
+// SetCoins replaces the tracked coin list, e.g. when a SIGHUP config reload
+// adds or removes a coin. Existing price/history cache entries for coins
+// that remain are left alone; ones no longer configured simply stop being
+// requested on the next fetch.
+func (s *Service) SetCoins(coins []config.CoinConfig) {
+	s.coinsMu.Lock()
+	s.coins = coins
+	s.coinsMu.Unlock()
+}
+
+// coinsSnapshot returns the current coin list, safe to range over even if
+// SetCoins replaces it concurrently.
+func (s *Service) coinsSnapshot() []config.CoinConfig {
+	s.coinsMu.RLock()
+	defer s.coinsMu.RUnlock()
+	return s.coins
+}
+
+// SetBaseURL overrides the CoinGecko API root used for pings, catalog
+// lookups, and price fetches. It defaults to the public CoinGecko API (or
+// the Pro API if COINGECKO_API_KEY is set); override it to point at an
+// httptest.NewServer in tests or an internal caching proxy in enterprise
+// deployments.
+func (s *Service) SetBaseURL(baseURL string) {
+	s.pingURL = baseURL + "/ping"
+	s.catalogURL = baseURL + "/coins/list"
+	s.priceURL = baseURL + "/simple/price"
+	s.marketChartURL = baseURL + "/coins/%s/market_chart"
+	s.supportedVsCurrenciesURL = baseURL + "/simple/supported_vs_currencies"
+}
+
+// setAPIKeyHeader attaches the Pro API key header to req if one is
+// configured. It's a no-op against the public API.
+func (s *Service) setAPIKeyHeader(req *http.Request) {
+	if s.apiKey != "" {
+		req.Header.Set(apiKeyHeader, s.apiKey)
+	}
+}
+
+// SetCatalogCachePath enables persisting the coins/list catalog to disk so
+// it survives restarts. Pass an empty string to disable persistence.
+func (s *Service) SetCatalogCachePath(path string) {
+	s.catalogCachePath = path
+}
+
+// SetVsCurrency changes the fiat currency prices are quoted in (e.g. "eur").
+// Defaults to "usd". Invalidates the price cache so the next call fetches
+// fresh data in the new currency.
+func (s *Service) SetVsCurrency(currency string) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	s.vsCurrency = currency
+	s.cache = nil
+	s.cacheTime = time.Time{}
+}
+
+// SetCacheTTL changes how long fetched prices are cached before the next
+// call re-fetches from the API. A zero or negative TTL falls back to
+// defaultCacheTTL rather than disabling caching or fetching on every call.
+func (s *Service) SetCacheTTL(ttl time.Duration) {
+	s.cacheMu.Lock()
+	defer s.cacheMu.Unlock()
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
 	}
+	s.cacheTTL = ttl
 }
 
-// CoinGeckoResponse represents the API response structure
-type CoinGeckoResponse map[string]struct {
-	USD          float64 `json:"usd"`
-	USD24hChange float64 `json:"usd_24h_change"`
+// SetRetryConfig changes how many times doFetchPrices retries a failed
+// CoinGecko price fetch and the base delay its exponential backoff grows
+// from. A zero or negative attempts/baseDelay falls back to
+// defaultRetryAttempts/defaultRetryBaseDelay respectively, rather than
+// disabling retries or backoff entirely.
+func (s *Service) SetRetryConfig(attempts int, baseDelay time.Duration) {
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+	s.retryAttempts = attempts
+	s.retryBaseDelay = baseDelay
 }
 
+// CacheStats reports the current price cache size and its age in seconds,
+// for runtime diagnostics (e.g. a selftest endpoint). Age is 0 when the
+// cache has never been populated.
+func (s *Service) CacheStats() (size int, ageSeconds float64) {
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+
+	if len(s.cache) == 0 {
+		return 0, 0
+	}
+	return len(s.cache), time.Since(s.cacheTime).Seconds()
+}
+
+// PortfolioHolding is one held coin's contribution to a PortfolioValue
+// total.
+type PortfolioHolding struct {
+	ID       string
+	Holdings float64
+	Price    float64
+	Value    float64
+}
+
+// PortfolioValue is the result of Service.PortfolioValue: the total value of
+// every configured holding, priced at the latest cached/fetched prices.
+type PortfolioValue struct {
+	Total float64
+	// Holdings lists only the coins that priced successfully; a held coin
+	// missing from Holdings means its price couldn't be determined, and
+	// Partial is set.
+	Holdings []PortfolioHolding
+	// Partial is true when at least one held coin's price couldn't be
+	// determined (upstream fetch failed, or the coin was absent from the
+	// price set), meaning Total understates the real portfolio value.
+	Partial bool
+}
+
+// PortfolioValue sums Holdings * Price across every coin configured with a
+// non-zero CoinConfig.Holdings, using GetPrices (so it benefits from the
+// same cache/fallback behavior as the ticker). A coin with holdings whose
+// price can't be determined is skipped and marks the result Partial rather
+// than failing the whole computation.
+func (s *Service) PortfolioValue() PortfolioValue {
+	prices, err := s.GetPrices()
+
+	priceByID := make(map[string]Coin, len(prices))
+	for _, c := range prices {
+		priceByID[c.ID] = c
+	}
+
+	result := PortfolioValue{Partial: err != nil}
+	for _, cfg := range s.coinsSnapshot() {
+		if cfg.Holdings == 0 {
+			continue
+		}
+
+		coin, ok := priceByID[cfg.ID]
+		if !ok {
+			result.Partial = true
+			continue
+		}
+
+		value := cfg.Holdings * coin.Price
+		result.Total += value
+		result.Holdings = append(result.Holdings, PortfolioHolding{
+			ID:       cfg.ID,
+			Holdings: cfg.Holdings,
+			Price:    coin.Price,
+			Value:    value,
+		})
+	}
+
+	return result
+}
+
+// Ping performs a lightweight liveness check against CoinGecko. It's meant
+// for deeper health checks (e.g. /api/health?verbose=1), not the hot path.
+func (s *Service) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.pingURL, nil)
+	if err != nil {
+		return err
+	}
+	s.setAPIKeyHeader(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("coingecko ping returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// CoinGeckoResponse represents the API response structure: coin ID -> field
+// name -> value. Field names depend on the requested vs_currency, e.g. "eur"
+// and "eur_24h_change", so this can't be a fixed struct.
+type CoinGeckoResponse map[string]map[string]float64
+
 // ErrCoinNotFound is returned when a coin is not in the tracked list
 var ErrCoinNotFound = errors.New("coin not found")
 
@@ -60,21 +522,100 @@ func (s *Service) GetPrices() ([]Coin, error) {
 	}
 	s.cacheMu.RUnlock()
 
-	// Build ID list from config
-	ids := make([]string, len(s.coins))
-	for i, c := range s.coins {
-		ids[i] = c.ID
+	return s.fetchAndCachePrices()
+}
+
+// fetchAndCachePrices fetches current prices for all tracked coins from
+// CoinGecko on a stale cache miss. refreshMu serializes callers so
+// concurrent cache misses coalesce into a single upstream fetch instead of
+// stampeding CoinGecko: a caller that waits for refreshMu behind another
+// reuses whatever cache that first caller just filled in, rather than
+// fetching again itself.
+func (s *Service) fetchAndCachePrices() ([]Coin, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.cacheMu.RLock()
+	fresh := time.Since(s.cacheTime) < s.cacheTTL && len(s.cache) > 0
+	if fresh {
+		coins := make([]Coin, len(s.cache))
+		copy(coins, s.cache)
+		s.cacheMu.RUnlock()
+		return coins, nil
+	}
+	s.cacheMu.RUnlock()
+
+	return s.doFetchPrices()
+}
+
+// forceFetchPrices fetches current prices for all tracked coins from
+// CoinGecko, ignoring the cache TTL entirely. Like fetchAndCachePrices, it
+// serializes on refreshMu, but a caller that waits behind an in-flight
+// forced fetch which started after it did reuses that result instead of
+// stampeding CoinGecko with a second forced request.
+func (s *Service) forceFetchPrices() ([]Coin, error) {
+	callStart := time.Now()
+
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+
+	s.cacheMu.RLock()
+	alreadyRefreshed := s.cacheTime.After(callStart)
+	if alreadyRefreshed {
+		coins := make([]Coin, len(s.cache))
+		copy(coins, s.cache)
+		s.cacheMu.RUnlock()
+		return coins, nil
+	}
+	s.cacheMu.RUnlock()
+
+	return s.doFetchPrices()
+}
+
+// doFetchPrices performs the actual upstream fetch and stores the result as
+// the new cache. Callers must hold refreshMu; use fetchAndCachePrices or
+// forceFetchPrices instead of calling this directly.
+func (s *Service) doFetchPrices() ([]Coin, error) {
+	if s.isRateLimited() {
+		return s.fallbackPrices(), nil
+	}
+
+	// Build ID list from config, excluding coins CoinGecko has recently told
+	// us it doesn't recognize (see the missing-from-response handling below)
+	// - retrying those every cycle wastes a request slot on something that
+	// can't succeed, at least until unavailableRecheckInterval passes.
+	ids := make([]string, 0, len(s.coinsSnapshot()))
+	for _, c := range s.coinsSnapshot() {
+		if !s.isUnavailable(c.ID) {
+			ids = append(ids, c.ID)
+		}
+	}
+	if len(ids) == 0 {
+		return s.fallbackPrices(), nil
 	}
 	idStr := strings.Join(ids, ",")
-	url := "https://api.coingecko.com/api/v3/simple/price?ids=" + idStr + "&vs_currencies=usd&include_24hr_change=true"
+	vsCurrency := s.vsCurrency
+	if vsCurrency == "" {
+		vsCurrency = defaultVsCurrency
+	}
+	url := s.priceURL + "?ids=" + idStr + "&vs_currencies=" + vsCurrency + "&include_24hr_change=true"
 
-	resp, err := s.client.Get(url)
+	resp, err := s.getWithRetry(url)
 	if err != nil {
 		return s.fallbackPrices(), nil
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
+		// A 4xx here (bad API key, malformed query, one bad ID among many)
+		// applies to the whole batched request, not any single coin in it -
+		// there's nothing in the status alone that attributes it to one ID,
+		// so it isn't grounds for markUnavailable. CoinGecko instead reports
+		// an unrecognized ID by silently omitting it from an otherwise-200
+		// response, which is handled below.
+		if resp.StatusCode >= http.StatusBadRequest && resp.StatusCode < http.StatusInternalServerError {
+			slog.Warn("coingecko_client_error", "status", resp.StatusCode, "ids", idStr)
+		}
 		return s.fallbackPrices(), nil
 	}
 
@@ -83,19 +624,42 @@ func (s *Service) GetPrices() ([]Coin, error) {
 		return s.fallbackPrices(), nil
 	}
 
-	coins := make([]Coin, 0, len(s.coins))
-	for _, cfg := range s.coins {
+	requested := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		requested[id] = true
+	}
+
+	coins := make([]Coin, 0, len(s.coinsSnapshot()))
+	for _, cfg := range s.coinsSnapshot() {
 		if coinData, ok := data[cfg.ID]; ok {
 			coins = append(coins, Coin{
 				ID:          cfg.ID,
 				DisplayName: cfg.DisplayName,
-				Price:       coinData.USD,
-				Change24h:   coinData.USD24hChange,
+				Price:       coinData[vsCurrency],
+				Change24h:   coinData[vsCurrency+"_24h_change"],
+				Currency:    vsCurrency,
 			})
+		} else if requested[cfg.ID] {
+			// CoinGecko silently omits IDs it doesn't recognize from an
+			// otherwise-200 response rather than erroring the whole batch -
+			// that omission, not a bare 4xx on the batch, is what actually
+			// attributes unavailability to this specific coin.
+			slog.Warn("coingecko_coin_unavailable", "id", cfg.ID)
+			s.markUnavailable([]string{cfg.ID})
 		}
 	}
 
 	s.cacheMu.Lock()
+	oldByID := make(map[string]Coin, len(s.cache))
+	for _, c := range s.cache {
+		oldByID[c.ID] = c
+	}
+	s.generation++
+	for _, c := range coins {
+		if prev, existed := oldByID[c.ID]; !existed || prev.Price != c.Price || prev.Change24h != c.Change24h {
+			s.changedAt[c.ID] = s.generation
+		}
+	}
 	s.cache = coins
 	s.cacheTime = time.Now()
 	s.cacheMu.Unlock()
@@ -103,6 +667,227 @@ func (s *Service) GetPrices() ([]Coin, error) {
 	return coins, nil
 }
 
+// getWithRetry GETs url, retrying transient failures (network errors and 5xx
+// responses) with jittered exponential backoff up to s.retryAttempts times.
+// All attempts share a single overall deadline equal to the client's
+// configured timeout, so retries can't stack into a hang longer than a
+// single request would already allow.
+func (s *Service) getWithRetry(url string) (*http.Response, error) {
+	budget := s.client.Timeout
+	if budget <= 0 {
+		budget = defaultClientTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), budget)
+	defer cancel()
+
+	attempts := s.retryAttempts
+	if attempts <= 0 {
+		attempts = defaultRetryAttempts
+	}
+	baseDelay := s.retryBaseDelay
+	if baseDelay <= 0 {
+		baseDelay = defaultRetryBaseDelay
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-time.After(jitteredBackoff(baseDelay, attempt)):
+			case <-ctx.Done():
+				return nil, lastErr
+			}
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return nil, err
+		}
+		s.setAPIKeyHeader(req)
+
+		resp, err := s.client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if resp.StatusCode == http.StatusTooManyRequests {
+			retryAfter := parseRetryAfter(resp.Header.Get("Retry-After"))
+			resp.Body.Close()
+			until := s.markRateLimited(retryAfter)
+			slog.Warn("coingecko_rate_limited", "retry_after", retryAfter.String(), "until", until)
+			return nil, fmt.Errorf("coingecko price fetch rate limited, retry after %s", retryAfter)
+		}
+		if resp.StatusCode >= http.StatusInternalServerError {
+			lastErr = fmt.Errorf("coingecko price fetch returned status %d", resp.StatusCode)
+			resp.Body.Close()
+			continue
+		}
+
+		return resp, nil
+	}
+
+	return nil, lastErr
+}
+
+// markUnavailable records ids as coins CoinGecko didn't recognize, so
+// doFetchPrices stops requesting them for unavailableRecheckInterval -
+// long enough that a genuinely bad ID doesn't waste a request slot every
+// cycle, but bounded so a coin that's only temporarily missing (or gets
+// listed later) isn't dropped forever.
+func (s *Service) markUnavailable(ids []string) {
+	s.unavailableMu.Lock()
+	defer s.unavailableMu.Unlock()
+	for _, id := range ids {
+		s.unavailableCoins[id] = s.clock.Now()
+	}
+}
+
+// isUnavailable reports whether id was marked unavailable within the last
+// unavailableRecheckInterval, i.e. whether doFetchPrices should still
+// exclude it from the batched price request.
+func (s *Service) isUnavailable(id string) bool {
+	s.unavailableMu.RLock()
+	defer s.unavailableMu.RUnlock()
+	markedAt, ok := s.unavailableCoins[id]
+	if !ok {
+		return false
+	}
+	return s.clock.Now().Sub(markedAt) < unavailableRecheckInterval
+}
+
+// IsCoinUnavailable reports whether id is currently excluded from price
+// fetches because CoinGecko didn't recognize it in a recent response. Callers
+// use this to surface a coin as unavailable (e.g. in an admin view) rather
+// than as a transient fetch failure.
+func (s *Service) IsCoinUnavailable(id string) bool {
+	return s.isUnavailable(id)
+}
+
+// isRateLimited reports whether CoinGecko's most recent 429 response's
+// Retry-After deadline hasn't passed yet, so doFetchPrices can skip the
+// upstream fetch entirely and serve cached/fallback data instead.
+func (s *Service) isRateLimited() bool {
+	s.rateLimitMu.RLock()
+	defer s.rateLimitMu.RUnlock()
+	return time.Now().Before(s.rateLimitedUntil)
+}
+
+// IsRateLimited reports whether the service is currently backing off from
+// CoinGecko after a 429, serving cached/fallback data instead of live
+// prices. Callers use this to surface a degraded state to clients (e.g. a
+// dashboard banner or an API response's Retry-After) even though GetPrices
+// itself still succeeds by falling back rather than returning an error.
+func (s *Service) IsRateLimited() bool {
+	return s.isRateLimited()
+}
+
+// RateLimitRetryAfter returns how long callers should wait before CoinGecko
+// requests are likely to succeed again, based on the most recent 429's
+// Retry-After deadline. Zero means the service isn't currently rate limited.
+func (s *Service) RateLimitRetryAfter() time.Duration {
+	s.rateLimitMu.RLock()
+	defer s.rateLimitMu.RUnlock()
+	remaining := time.Until(s.rateLimitedUntil)
+	if remaining <= 0 {
+		return 0
+	}
+	return remaining
+}
+
+// markRateLimited records that CoinGecko asked us to back off for
+// retryAfter and returns the resulting deadline.
+func (s *Service) markRateLimited(retryAfter time.Duration) time.Time {
+	until := time.Now().Add(retryAfter)
+	s.rateLimitMu.Lock()
+	s.rateLimitedUntil = until
+	s.rateLimitMu.Unlock()
+	return until
+}
+
+// defaultRetryAfter is used when a 429 response omits Retry-After or sends
+// one this can't parse, so a malformed header still results in backing off
+// rather than fetching again immediately.
+const defaultRetryAfter = 60 * time.Second
+
+// parseRetryAfter parses a Retry-After header value, which per RFC 9110 is
+// either a number of seconds or an HTTP date.
+func parseRetryAfter(header string) time.Duration {
+	if header == "" {
+		return defaultRetryAfter
+	}
+	if seconds, err := strconv.Atoi(header); err == nil {
+		if seconds < 0 {
+			return defaultRetryAfter
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if when, err := http.ParseTime(header); err == nil {
+		if d := time.Until(when); d > 0 {
+			return d
+		}
+	}
+	return defaultRetryAfter
+}
+
+// jitteredBackoff returns the delay before retry attempt (1-indexed), growing
+// exponentially from base and randomized by up to +/-50% so concurrent
+// callers retrying after the same failure don't all hammer CoinGecko at
+// once.
+func jitteredBackoff(base time.Duration, attempt int) time.Duration {
+	backoff := base * time.Duration(1<<uint(attempt-1))
+	jitter := time.Duration(rand.Int63n(int64(backoff))) - backoff/2
+	return backoff + jitter
+}
+
+// RefreshCoin forces a fresh upstream fetch of all tracked coins' prices
+// and returns just id's updated row, bypassing the cache TTL. It's used by
+// the manual per-coin refresh endpoint, so an operator who suspects a
+// stale value can confirm it without waiting out the cache. Concurrent
+// refreshes are coalesced by forceFetchPrices rather than stampeding
+// CoinGecko with duplicate requests.
+func (s *Service) RefreshCoin(id string) (*Coin, error) {
+	tracked := false
+	for _, cfg := range s.coinsSnapshot() {
+		if cfg.ID == id {
+			tracked = true
+			break
+		}
+	}
+	if !tracked {
+		return nil, ErrCoinNotFound
+	}
+
+	coins, err := s.forceFetchPrices()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, coin := range coins {
+		if coin.ID == id {
+			return &coin, nil
+		}
+	}
+
+	return nil, ErrCoinNotFound
+}
+
+// GetPriceMap is like GetPrices but returns the coins keyed by ID, so a
+// caller that needs to look several up (e.g. a template rendering a
+// portfolio table) avoids repeated linear scans over the slice. The
+// returned map is a fresh copy; mutating it doesn't affect the cache.
+func (s *Service) GetPriceMap() (map[string]Coin, error) {
+	coins, err := s.GetPrices()
+	if err != nil {
+		return nil, err
+	}
+
+	byID := make(map[string]Coin, len(coins))
+	for _, coin := range coins {
+		byID[coin.ID] = coin
+	}
+	return byID, nil
+}
+
 // GetCoin fetches a single coin by ID
 func (s *Service) GetCoin(id string) (*Coin, error) {
 	coins, err := s.GetPrices()
@@ -142,6 +927,329 @@ func (s *Service) SearchCoins(query string) ([]Coin, error) {
 	return filtered, nil
 }
 
+// Changes returns the coins whose price has changed since the given
+// generation, along with the current generation number, so a client can
+// poll cheaply instead of re-fetching every coin on its own schedule.
+func (s *Service) Changes(since int64) ([]Coin, int64, error) {
+	coins, err := s.GetPrices()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s.cacheMu.RLock()
+	defer s.cacheMu.RUnlock()
+
+	changed := make([]Coin, 0)
+	for _, c := range coins {
+		if s.changedAt[c.ID] > since {
+			changed = append(changed, c)
+		}
+	}
+
+	return changed, s.generation, nil
+}
+
+// GetHistory returns days worth of daily price samples for coinID, for
+// rendering a sparkline. Like GetPrices, it never itself returns a non-nil
+// error: an upstream failure falls back to a synthetic series derived from
+// fallbackPrices, consistent with how GetPrices degrades.
+func (s *Service) GetHistory(coinID string, days int) ([]PricePoint, error) {
+	key := historyCacheKey(coinID, days)
+
+	if points, ok := s.historyCache.get(key); ok {
+		return points, nil
+	}
+
+	points, err := s.fetchHistory(coinID, days)
+	if err != nil {
+		return s.fallbackHistory(coinID, days), nil
+	}
+
+	s.historyCache.set(key, points)
+
+	return points, nil
+}
+
+// SetHistoryCacheSize changes how many (coin, days) history entries
+// GetHistory's LRU keeps at once. Zero or negative falls back to
+// defaultHistoryCacheSize. Shrinking below the current entry count evicts
+// the least-recently-used entries immediately.
+func (s *Service) SetHistoryCacheSize(size int) {
+	if size <= 0 {
+		size = defaultHistoryCacheSize
+	}
+	s.historyCache.setSize(size)
+}
+
+func historyCacheKey(coinID string, days int) string {
+	return coinID + ":" + strconv.Itoa(days)
+}
+
+// marketChartResponse is CoinGecko's market_chart response shape: each entry
+// in prices is a [timestamp_ms, price] pair.
+type marketChartResponse struct {
+	Prices [][2]float64 `json:"prices"`
+}
+
+// fetchHistory fetches coinID's price history over the last days days from
+// CoinGecko's market_chart endpoint.
+func (s *Service) fetchHistory(coinID string, days int) ([]PricePoint, error) {
+	url := fmt.Sprintf(s.marketChartURL, coinID) + "?vs_currency=" + s.currencyOrDefault() + "&days=" + strconv.Itoa(days)
+
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.setAPIKeyHeader(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko market_chart fetch returned status %d", resp.StatusCode)
+	}
+
+	var data marketChartResponse
+	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
+		return nil, err
+	}
+
+	points := make([]PricePoint, len(data.Prices))
+	for i, p := range data.Prices {
+		points[i] = PricePoint{
+			Timestamp: time.UnixMilli(int64(p[0])),
+			Price:     p[1],
+		}
+	}
+
+	return points, nil
+}
+
+// currencyOrDefault returns the configured vs_currency, falling back to
+// defaultVsCurrency when unset.
+func (s *Service) currencyOrDefault() string {
+	if s.vsCurrency == "" {
+		return defaultVsCurrency
+	}
+	return s.vsCurrency
+}
+
+// fallbackHistory synthesizes a deterministic price series around a base
+// price sourced from fallbackPrices, so a sparkline still renders something
+// plausible when CoinGecko's market_chart endpoint is unavailable.
+func (s *Service) fallbackHistory(coinID string, days int) []PricePoint {
+	basePrice := 0.0
+	for _, c := range s.fallbackPrices() {
+		if c.ID == coinID {
+			basePrice = c.Price
+			break
+		}
+	}
+
+	if days <= 0 {
+		days = 1
+	}
+
+	now := time.Now()
+	points := make([]PricePoint, days)
+	for i := 0; i < days; i++ {
+		wobble := 1 + 0.02*math.Sin(float64(i))
+		points[i] = PricePoint{
+			Timestamp: now.Add(-time.Duration(days-1-i) * 24 * time.Hour),
+			Price:     basePrice * wobble,
+		}
+	}
+
+	return points
+}
+
+// Catalog returns CoinGecko's full coins/list catalog (id, symbol, name for
+// every coin it tracks), backed by a long-lived cache since the list is
+// large and rarely changes. Features like ID validation, symbol lookup, and
+// catalog search should all go through this so they share one cached fetch.
+func (s *Service) Catalog(ctx context.Context) ([]CatalogEntry, error) {
+	s.catalogMu.RLock()
+	if len(s.catalogCache) > 0 && s.clock.Now().Sub(s.catalogCacheTime) < catalogCacheTTL {
+		entries := make([]CatalogEntry, len(s.catalogCache))
+		copy(entries, s.catalogCache)
+		s.catalogMu.RUnlock()
+		return entries, nil
+	}
+	s.catalogMu.RUnlock()
+
+	entries, err := s.fetchCatalog(ctx)
+	if err != nil {
+		if cached := s.staleCatalog(); cached != nil {
+			return cached, nil
+		}
+		if loaded, loadErr := s.loadCatalogFromDisk(); loadErr == nil {
+			s.storeCatalog(loaded)
+			return loaded, nil
+		}
+		return nil, err
+	}
+
+	s.storeCatalog(entries)
+	if s.catalogCachePath != "" {
+		if saveErr := s.saveCatalogToDisk(entries); saveErr != nil {
+			return entries, nil // catalog fetch succeeded; persistence is best-effort
+		}
+	}
+
+	return entries, nil
+}
+
+// staleCatalog returns the in-memory catalog cache regardless of TTL, or
+// nil if nothing has been cached yet.
+func (s *Service) staleCatalog() []CatalogEntry {
+	s.catalogMu.RLock()
+	defer s.catalogMu.RUnlock()
+	if len(s.catalogCache) == 0 {
+		return nil
+	}
+	entries := make([]CatalogEntry, len(s.catalogCache))
+	copy(entries, s.catalogCache)
+	return entries
+}
+
+func (s *Service) storeCatalog(entries []CatalogEntry) {
+	s.catalogMu.Lock()
+	defer s.catalogMu.Unlock()
+	s.catalogCache = entries
+	s.catalogCacheTime = s.clock.Now()
+}
+
+func (s *Service) fetchCatalog(ctx context.Context) ([]CatalogEntry, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.catalogURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.setAPIKeyHeader(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko catalog fetch returned status %d", resp.StatusCode)
+	}
+
+	var entries []CatalogEntry
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *Service) loadCatalogFromDisk() ([]CatalogEntry, error) {
+	if s.catalogCachePath == "" {
+		return nil, errors.New("catalog disk persistence not configured")
+	}
+
+	data, err := os.ReadFile(s.catalogCachePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []CatalogEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+func (s *Service) saveCatalogToDisk(entries []CatalogEntry) error {
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.catalogCachePath, data, 0644)
+}
+
+// ValidateVsCurrency reports whether the configured vs_currency is one
+// CoinGecko actually supports, so a typo like "usdd" is caught at startup
+// instead of silently showing up as a wall of zeros (CoinGecko just omits
+// the price fields for a currency it doesn't recognize). The supported list
+// is fetched from supported_vs_currencies and cached for catalogCacheTTL,
+// the same lifetime as the coins/list catalog, since both change rarely.
+func (s *Service) ValidateVsCurrency(ctx context.Context) error {
+	supported, err := s.getSupportedVsCurrencies(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch supported vs_currencies: %w", err)
+	}
+
+	currency := strings.ToLower(s.currencyOrDefault())
+	if !supported[currency] {
+		return fmt.Errorf("vs_currency %q is not in CoinGecko's supported_vs_currencies list", currency)
+	}
+	return nil
+}
+
+func (s *Service) getSupportedVsCurrencies(ctx context.Context) (map[string]bool, error) {
+	s.supportedVsCurrenciesMu.RLock()
+	if s.supportedVsCurrencies != nil && s.clock.Now().Sub(s.supportedVsCurrenciesTime) < catalogCacheTTL {
+		cached := s.supportedVsCurrencies
+		s.supportedVsCurrenciesMu.RUnlock()
+		return cached, nil
+	}
+	s.supportedVsCurrenciesMu.RUnlock()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.supportedVsCurrenciesURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	s.setAPIKeyHeader(req)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("coingecko supported_vs_currencies fetch returned status %d", resp.StatusCode)
+	}
+
+	var currencies []string
+	if err := json.NewDecoder(resp.Body).Decode(&currencies); err != nil {
+		return nil, err
+	}
+
+	supported := make(map[string]bool, len(currencies))
+	for _, currency := range currencies {
+		supported[strings.ToLower(currency)] = true
+	}
+
+	s.supportedVsCurrenciesMu.Lock()
+	s.supportedVsCurrencies = supported
+	s.supportedVsCurrenciesTime = s.clock.Now()
+	s.supportedVsCurrenciesMu.Unlock()
+
+	return supported, nil
+}
+
+// mockCurrencyRates approximates other currencies relative to the USD mock
+// prices below, purely so the offline fallback doesn't display USD figures
+// mislabeled as another currency. Unknown currencies fall back to 1.0 (USD).
+var mockCurrencyRates = map[string]float64{
+	"usd": 1.0,
+	"eur": 0.92,
+	"gbp": 0.79,
+	"jpy": 149.50,
+}
+
+// defaultFallbackPrice is used for a configured coin that has neither
+// upstream data nor a built-in mock price nor its own CoinConfig.FallbackPrice,
+// so it still shows up in fallbackPrices instead of silently vanishing.
+const defaultFallbackPrice = 1.00
+
 // fallbackPrices returns cached or mock data when API is unavailable
 func (s *Service) fallbackPrices() []Coin {
 	s.cacheMu.RLock()
@@ -153,27 +1261,41 @@ func (s *Service) fallbackPrices() []Coin {
 	}
 	s.cacheMu.RUnlock()
 
-	// Build display name map
-	nameMap := make(map[string]string)
-	for _, c := range s.coins {
-		nameMap[c.ID] = c.DisplayName
+	vsCurrency := s.vsCurrency
+	if vsCurrency == "" {
+		vsCurrency = defaultVsCurrency
+	}
+	rate, ok := mockCurrencyRates[vsCurrency]
+	if !ok {
+		rate = 1.0
 	}
 
-	// Return mock data as last resort
-	mockData := []Coin{
-		{ID: "bitcoin", Price: 43250.00, Change24h: 2.35},
-		{ID: "ethereum", Price: 2280.50, Change24h: 1.87},
-		{ID: "dogecoin", Price: 0.0825, Change24h: -0.42},
-		{ID: "solana", Price: 98.75, Change24h: 5.12},
-		{ID: "cardano", Price: 0.52, Change24h: -1.23},
+	// mockPrices seeds well-known coins with realistic-looking prices; any
+	// configured coin not in this set still appears via CoinConfig.FallbackPrice
+	// (or defaultFallbackPrice) below.
+	mockPrices := map[string]Coin{
+		"bitcoin":  {Price: 43250.00, Change24h: 2.35},
+		"ethereum": {Price: 2280.50, Change24h: 1.87},
+		"dogecoin": {Price: 0.0825, Change24h: -0.42},
+		"solana":   {Price: 98.75, Change24h: 5.12},
+		"cardano":  {Price: 0.52, Change24h: -1.23},
 	}
 
-	result := make([]Coin, 0)
-	for _, mock := range mockData {
-		if name, ok := nameMap[mock.ID]; ok {
-			mock.DisplayName = name
-			result = append(result, mock)
+	result := make([]Coin, 0, len(s.coinsSnapshot()))
+	for _, cfg := range s.coinsSnapshot() {
+		coin, ok := mockPrices[cfg.ID]
+		if !ok {
+			price := cfg.FallbackPrice
+			if price == 0 {
+				price = defaultFallbackPrice
+			}
+			coin = Coin{Price: price}
 		}
+		coin.ID = cfg.ID
+		coin.DisplayName = cfg.DisplayName
+		coin.Price *= rate
+		coin.Currency = vsCurrency
+		result = append(result, coin)
 	}
 
 	return result
@@ -1,14 +1,17 @@
 package coingecko
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -29,8 +32,23 @@ func TestNewService(t *testing.T) {
 	assert.Equal(t, 30*time.Second, service.cacheTTL)
 }
 
+func TestSetCoins_ReplacesTrackedCoins(t *testing.T) {
+	service := NewService([]config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	})
+
+	service.SetCoins([]config.CoinConfig{
+		{ID: "solana", DisplayName: "Solana"},
+		{ID: "cardano", DisplayName: "Cardano"},
+	})
+
+	snapshot := service.coinsSnapshot()
+	require.Len(t, snapshot, 2)
+	assert.Equal(t, "solana", snapshot[0].ID)
+	assert.Equal(t, "cardano", snapshot[1].ID)
+}
+
 func TestGetPrices_Success(t *testing.T) {
-	// Create a mock server
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		w.Write([]byte(`{
@@ -46,11 +64,33 @@ func TestGetPrices_Success(t *testing.T) {
 	}
 
 	service := NewService(coins)
-	// Override the client to use our test server
 	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 2)
+
+	byID := map[string]Coin{}
+	for _, p := range prices {
+		byID[p.ID] = p
+	}
+	assert.Equal(t, 50000.00, byID["bitcoin"].Price)
+	assert.Equal(t, 2.5, byID["bitcoin"].Change24h)
+	assert.Equal(t, 3000.00, byID["ethereum"].Price)
+	assert.Equal(t, -1.2, byID["ethereum"].Change24h)
+}
 
-	// We need to make the service use our server URL
-	// Since we can't easily change the URL, let's test the fallback behavior instead
+func TestSetBaseURL_OverridesAllEndpoints(t *testing.T) {
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+
+	service.SetBaseURL("http://example.test/v3")
+
+	assert.Equal(t, "http://example.test/v3/ping", service.pingURL)
+	assert.Equal(t, "http://example.test/v3/coins/list", service.catalogURL)
+	assert.Equal(t, "http://example.test/v3/simple/price", service.priceURL)
 }
 
 func TestGetPrices_APIFailure_FallsBackToMock(t *testing.T) {
@@ -152,6 +192,87 @@ func TestGetCoin_NotFound(t *testing.T) {
 	assert.ErrorIs(t, err, ErrCoinNotFound)
 }
 
+func TestRefreshCoin_ForcesFetchAndReturnsCoin(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 51000.00, "usd_24h_change": 1.5}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	// Pre-populate a still-fresh cache; RefreshCoin should bypass it.
+	service.cache = []Coin{{ID: "bitcoin", DisplayName: "Bitcoin", Price: 1.00}}
+	service.cacheTime = time.Now()
+
+	coin, err := service.RefreshCoin("bitcoin")
+
+	require.NoError(t, err)
+	require.NotNil(t, coin)
+	assert.Equal(t, "bitcoin", coin.ID)
+	assert.Equal(t, 51000.00, coin.Price)
+	assert.Equal(t, 1, requests)
+}
+
+func TestRefreshCoin_UnknownCoinReturnsNotFoundWithoutFetching(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	coin, err := service.RefreshCoin("dogecoin")
+
+	assert.Nil(t, coin)
+	assert.ErrorIs(t, err, ErrCoinNotFound)
+	assert.Equal(t, 0, requests, "should not hit the upstream API for an untracked coin")
+}
+
+func TestRefreshCoin_ConcurrentCallsCoalesceIntoOneFetch(t *testing.T) {
+	var requests int
+	var mu sync.Mutex
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		requests++
+		mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 51000.00, "usd_24h_change": 1.5}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := service.RefreshCoin("bitcoin")
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Less(t, requests, 5, "concurrent refreshes should coalesce rather than each hitting the API")
+}
+
 func TestSearchCoins_EmptyQuery(t *testing.T) {
 	coins := []config.CoinConfig{
 		{ID: "bitcoin", DisplayName: "Bitcoin"},
@@ -301,6 +422,103 @@ func TestFallbackPrices_UsesMockData(t *testing.T) {
 	}
 }
 
+func TestFallbackPrices_DefaultsToUSD(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	}
+
+	service := NewService(coins)
+
+	fallback := service.fallbackPrices()
+
+	require.Len(t, fallback, 1)
+	assert.Equal(t, "usd", fallback[0].Currency)
+	assert.Equal(t, 43250.00, fallback[0].Price)
+}
+
+func TestFallbackPrices_ScalesForConfiguredCurrency(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	}
+
+	service := NewService(coins)
+	service.SetVsCurrency("eur")
+
+	fallback := service.fallbackPrices()
+
+	require.Len(t, fallback, 1)
+	assert.Equal(t, "eur", fallback[0].Currency)
+	assert.Equal(t, 43250.00*0.92, fallback[0].Price)
+}
+
+func TestFallbackPrices_UnknownCurrencyKeepsUSDRate(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	}
+
+	service := NewService(coins)
+	service.SetVsCurrency("xyz")
+
+	fallback := service.fallbackPrices()
+
+	require.Len(t, fallback, 1)
+	assert.Equal(t, "xyz", fallback[0].Currency)
+	assert.Equal(t, 43250.00, fallback[0].Price)
+}
+
+func TestSetVsCurrency_InvalidatesCache(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{{ID: "bitcoin", Price: 50000.00, Currency: "usd"}}
+	service.cacheTime = time.Now()
+
+	service.SetVsCurrency("eur")
+
+	assert.Empty(t, service.cache)
+	assert.True(t, service.cacheTime.IsZero())
+	assert.Equal(t, "eur", service.vsCurrency)
+}
+
+func TestSetCacheTTL_ChangesTTL(t *testing.T) {
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+
+	service.SetCacheTTL(5 * time.Minute)
+
+	assert.Equal(t, 5*time.Minute, service.cacheTTL)
+}
+
+func TestSetCacheTTL_ZeroOrNegativeFallsBackToDefault(t *testing.T) {
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+
+	service := NewService(coins)
+	service.SetCacheTTL(0)
+	assert.Equal(t, defaultCacheTTL, service.cacheTTL)
+
+	service.SetCacheTTL(-time.Second)
+	assert.Equal(t, defaultCacheTTL, service.cacheTTL)
+}
+
+func TestSetCacheTTL_LongerTTLPreventsRefetchWithinWindow(t *testing.T) {
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.SetCacheTTL(5 * time.Minute)
+
+	cached := []Coin{{ID: "bitcoin", DisplayName: "Bitcoin", Price: 12345.67}}
+	service.cache = cached
+	// Older than the default 30s TTL, but well within the configured 5m TTL.
+	service.cacheTime = time.Now().Add(-time.Minute)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, 12345.67, prices[0].Price)
+}
+
 func TestFallbackPrices_MergesDisplayNames(t *testing.T) {
 	coins := []config.CoinConfig{
 		{ID: "bitcoin", DisplayName: "Custom Bitcoin Name"},
@@ -315,6 +533,41 @@ func TestFallbackPrices_MergesDisplayNames(t *testing.T) {
 	assert.Equal(t, "Custom Bitcoin Name", fallback[0].DisplayName)
 }
 
+func TestFallbackPrices_UnknownCoinUsesConfiguredFallbackPrice(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+		{ID: "customcoin", DisplayName: "CustomCoin", FallbackPrice: 0.001},
+	}
+
+	service := NewService(coins)
+
+	fallback := service.fallbackPrices()
+
+	require.Len(t, fallback, 2)
+	var custom *Coin
+	for i, c := range fallback {
+		if c.ID == "customcoin" {
+			custom = &fallback[i]
+		}
+	}
+	require.NotNil(t, custom, "custom coin should still appear in the fallback")
+	assert.Equal(t, "CustomCoin", custom.DisplayName)
+	assert.Equal(t, 0.001, custom.Price)
+}
+
+func TestFallbackPrices_UnknownCoinWithoutFallbackPriceUsesDefault(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "customcoin", DisplayName: "CustomCoin"},
+	}
+
+	service := NewService(coins)
+
+	fallback := service.fallbackPrices()
+
+	require.Len(t, fallback, 1)
+	assert.Equal(t, defaultFallbackPrice, fallback[0].Price)
+}
+
 func TestCoin_Struct(t *testing.T) {
 	coin := Coin{
 		ID:          "bitcoin",
@@ -409,6 +662,53 @@ func TestGetPrices_ReturnsCopy(t *testing.T) {
 	assert.NotEqual(t, prices1[0].Price, prices2[0].Price)
 }
 
+func TestGetPriceMap_ReturnsCoinsKeyedByID(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+		{ID: "ethereum", DisplayName: "Ethereum"},
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{
+		{ID: "bitcoin", DisplayName: "Bitcoin", Price: 50000.00},
+		{ID: "ethereum", DisplayName: "Ethereum", Price: 3000.00},
+	}
+	service.cacheTime = time.Now()
+
+	byID, err := service.GetPriceMap()
+
+	require.NoError(t, err)
+	require.Len(t, byID, 2)
+	assert.Equal(t, 50000.00, byID["bitcoin"].Price)
+	assert.Equal(t, 3000.00, byID["ethereum"].Price)
+}
+
+func TestGetPriceMap_ReturnsCopy(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{
+		{ID: "bitcoin", DisplayName: "Bitcoin", Price: 50000.00},
+	}
+	service.cacheTime = time.Now()
+
+	byID1, _ := service.GetPriceMap()
+	byID2, _ := service.GetPriceMap()
+
+	// Modifying one map's entry shouldn't affect the other, or the cache.
+	entry := byID1["bitcoin"]
+	entry.Price = 99999.99
+	byID1["bitcoin"] = entry
+
+	assert.NotEqual(t, byID1["bitcoin"].Price, byID2["bitcoin"].Price)
+
+	cached, err := service.GetPrices()
+	require.NoError(t, err)
+	assert.Equal(t, 50000.00, cached[0].Price)
+}
+
 func TestSearchCoins_MatchesBothIDAndDisplayName(t *testing.T) {
 	coins := []config.CoinConfig{
 		{ID: "ethereum", DisplayName: "Ethereum"},
@@ -459,16 +759,793 @@ func TestService_ClientTimeout(t *testing.T) {
 	assert.Equal(t, 10*time.Second, service.client.Timeout)
 }
 
-func TestCoinGeckoResponse_JSON(t *testing.T) {
-	// Test that the response structure can unmarshal correctly
-	jsonData := `{"bitcoin": {"usd": 50000.00, "usd_24h_change": 2.5}}`
+func TestPing_Success(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{"gecko_says":"(V3) To the Moon!"}`))
+	}))
+	defer server.Close()
 
-	var response CoinGeckoResponse
-	decoder := json.NewDecoder(strings.NewReader(jsonData))
-	err := decoder.Decode(&response)
+	service := NewService(nil)
+	service.pingURL = server.URL
+
+	err := service.Ping(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestPing_NonOKStatus(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
 
+	service := NewService(nil)
+	service.pingURL = server.URL
+
+	err := service.Ping(context.Background())
+	assert.Error(t, err)
+}
+
+func TestPing_ContextCanceled(t *testing.T) {
+	service := NewService(nil)
+	service.pingURL = defaultPingURL
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := service.Ping(ctx)
+	assert.Error(t, err)
+}
+
+func TestCatalog_FetchesAndCaches(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"bitcoin","symbol":"btc","name":"Bitcoin"}]`))
+	}))
+	defer server.Close()
+
+	clock := clockwork.NewFakeClock()
+	service := NewServiceWithClock(nil, clock)
+	service.catalogURL = server.URL
+
+	entries, err := service.Catalog(context.Background())
 	require.NoError(t, err)
-	assert.Contains(t, response, "bitcoin")
-	assert.Equal(t, 50000.00, response["bitcoin"].USD)
-	assert.Equal(t, 2.5, response["bitcoin"].USD24hChange)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "bitcoin", entries[0].ID)
+	assert.Equal(t, 1, calls)
+
+	// Second call within the TTL should hit the in-memory cache, not the server.
+	entries, err = service.Catalog(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, 1, calls)
+}
+
+func TestCatalog_TTLExpiryTriggersRefetch(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"ethereum","symbol":"eth","name":"Ethereum"}]`))
+	}))
+	defer server.Close()
+
+	clock := clockwork.NewFakeClock()
+	service := NewServiceWithClock(nil, clock)
+	service.catalogURL = server.URL
+
+	_, err := service.Catalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, calls)
+
+	clock.Advance(catalogCacheTTL + time.Second)
+
+	_, err = service.Catalog(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestCatalog_FetchFailureFallsBackToStaleCache(t *testing.T) {
+	up := true
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !up {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"solana","symbol":"sol","name":"Solana"}]`))
+	}))
+	defer server.Close()
+
+	clock := clockwork.NewFakeClock()
+	service := NewServiceWithClock(nil, clock)
+	service.catalogURL = server.URL
+
+	_, err := service.Catalog(context.Background())
+	require.NoError(t, err)
+
+	up = false
+	clock.Advance(catalogCacheTTL + time.Second)
+
+	entries, err := service.Catalog(context.Background())
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "solana", entries[0].ID)
+}
+
+func TestCatalog_DiskPersistenceRoundTrip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"id":"cardano","symbol":"ada","name":"Cardano"}]`))
+	}))
+	defer server.Close()
+
+	path := t.TempDir() + "/catalog.json"
+
+	service := NewService(nil)
+	service.catalogURL = server.URL
+	service.SetCatalogCachePath(path)
+
+	_, err := service.Catalog(context.Background())
+	require.NoError(t, err)
+
+	loaded, err := service.loadCatalogFromDisk()
+	require.NoError(t, err)
+	require.Len(t, loaded, 1)
+	assert.Equal(t, "cardano", loaded[0].ID)
+}
+
+func TestValidateVsCurrency_SupportedCurrencyPasses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`["usd", "eur", "gbp"]`))
+	}))
+	defer server.Close()
+
+	service := NewService(nil)
+	service.SetBaseURL(server.URL)
+	service.SetVsCurrency("eur")
+
+	err := service.ValidateVsCurrency(context.Background())
+	assert.NoError(t, err)
+}
+
+func TestValidateVsCurrency_UnsupportedCurrencyFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`["usd", "eur", "gbp"]`))
+	}))
+	defer server.Close()
+
+	service := NewService(nil)
+	service.SetBaseURL(server.URL)
+	service.SetVsCurrency("usdd")
+
+	err := service.ValidateVsCurrency(context.Background())
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "usdd")
+}
+
+func TestValidateVsCurrency_CachesSupportedList(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`["usd"]`))
+	}))
+	defer server.Close()
+
+	service := NewService(nil)
+	service.SetBaseURL(server.URL)
+
+	require.NoError(t, service.ValidateVsCurrency(context.Background()))
+	require.NoError(t, service.ValidateVsCurrency(context.Background()))
+	assert.Equal(t, 1, requests, "second call within the TTL should be served from cache")
+}
+
+func TestChanges_ReturnsOnlyCoinsChangedSinceGeneration(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+		{ID: "ethereum", DisplayName: "Ethereum"},
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{
+		{ID: "bitcoin", DisplayName: "Bitcoin", Price: 50000.00},
+		{ID: "ethereum", DisplayName: "Ethereum", Price: 3000.00},
+	}
+	service.cacheTime = time.Now()
+	service.generation = 2
+	service.changedAt = map[string]int64{"bitcoin": 2, "ethereum": 1}
+
+	changed, generation, err := service.Changes(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), generation)
+	require.Len(t, changed, 1)
+	assert.Equal(t, "bitcoin", changed[0].ID)
+
+	changed, generation, err = service.Changes(0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), generation)
+	assert.Len(t, changed, 2)
+
+	changed, generation, err = service.Changes(2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), generation)
+	assert.Empty(t, changed)
+}
+
+func TestCoinGeckoResponse_JSON(t *testing.T) {
+	// Test that the response structure can unmarshal correctly
+	jsonData := `{"bitcoin": {"usd": 50000.00, "usd_24h_change": 2.5}}`
+
+	var response CoinGeckoResponse
+	decoder := json.NewDecoder(strings.NewReader(jsonData))
+	err := decoder.Decode(&response)
+
+	require.NoError(t, err)
+	assert.Contains(t, response, "bitcoin")
+	assert.Equal(t, 50000.00, response["bitcoin"]["usd"])
+	assert.Equal(t, 2.5, response["bitcoin"]["usd_24h_change"])
+}
+
+func TestGetHistory_FetchesAndCaches(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"prices": [[1700000000000, 50000.0], [1700086400000, 50500.0]]}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	points, err := service.GetHistory("bitcoin", 7)
+	require.NoError(t, err)
+	require.Len(t, points, 2)
+	assert.Equal(t, 50000.0, points[0].Price)
+	assert.Equal(t, time.UnixMilli(1700000000000), points[0].Timestamp)
+
+	// Second call within the TTL should be served from cache.
+	_, err = service.GetHistory("bitcoin", 7)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests, "second call within TTL should not re-fetch")
+}
+
+func TestGetHistory_DifferentDaysAreCachedSeparately(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"prices": [[1700000000000, 50000.0]]}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetHistory("bitcoin", 7)
+	require.NoError(t, err)
+	_, err = service.GetHistory("bitcoin", 30)
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, requests, "different day ranges should fetch independently")
+}
+
+func TestGetHistory_TTLExpiryTriggersRefetch(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"prices": [[1700000000000, 50000.0]]}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	clock := clockwork.NewFakeClock()
+	service := NewServiceWithClock(coins, clock)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetHistory("bitcoin", 7)
+	require.NoError(t, err)
+	assert.Equal(t, 1, requests)
+
+	clock.Advance(historyCacheTTL + time.Second)
+
+	_, err = service.GetHistory("bitcoin", 7)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "entry past its TTL should be re-fetched")
+}
+
+func TestGetHistory_LRUEvictsLeastRecentlyUsed(t *testing.T) {
+	var requests int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"prices": [[1700000000000, 50000.0]]}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.SetHistoryCacheSize(2)
+
+	_, err := service.GetHistory("bitcoin", 1)
+	require.NoError(t, err)
+	_, err = service.GetHistory("bitcoin", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests)
+
+	// Touching days=1 again makes days=2 the least-recently-used entry.
+	_, err = service.GetHistory("bitcoin", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "recently-used entry should still be cached")
+
+	// A third distinct key pushes the cache past its size of 2, evicting
+	// days=2 (the least-recently-used one) rather than days=1.
+	_, err = service.GetHistory("bitcoin", 3)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests)
+
+	_, err = service.GetHistory("bitcoin", 1)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests, "days=1 should have survived eviction")
+
+	_, err = service.GetHistory("bitcoin", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 4, requests, "days=2 should have been evicted as least-recently-used")
+}
+
+func TestGetHistory_FallsBackToSyntheticDataOnFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.cache = []Coin{{ID: "bitcoin", DisplayName: "Bitcoin", Price: 43250.00}}
+	service.cacheTime = time.Now()
+
+	points, err := service.GetHistory("bitcoin", 7)
+
+	require.NoError(t, err)
+	require.Len(t, points, 7)
+	for _, p := range points {
+		assert.InDelta(t, 43250.00, p.Price, 43250.00*0.03)
+	}
+}
+
+func TestFallbackHistory_UnknownCoinReturnsZeroPrices(t *testing.T) {
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+
+	points := service.fallbackHistory("nonexistent", 7)
+
+	require.Len(t, points, 7)
+	for _, p := range points {
+		assert.Equal(t, 0.0, p.Price)
+	}
+}
+
+func TestGetPrices_RetriesTransientFailureThenSucceeds(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requestCount, 1) <= 2 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00, "usd_24h_change": 2.5}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.SetRetryConfig(3, time.Millisecond)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, 50000.00, prices[0].Price)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requestCount))
+}
+
+func TestGetPrices_ExhaustsRetriesThenFallsBack(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.SetRetryConfig(3, time.Millisecond)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requestCount))
+}
+
+func TestGetPrices_ServiceUnavailable_RetriesLikeAny5xx(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.SetRetryConfig(3, time.Millisecond)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&requestCount), "a 503 should be retried like any other 5xx")
+	assert.False(t, service.IsCoinUnavailable("bitcoin"), "a transient 503 should not permanently mark the coin unavailable")
+}
+
+func TestGetPrices_NotFound_DoesNotMarkAnyCoinUnavailable(t *testing.T) {
+	// A 4xx on the batched request can't be attributed to any single coin in
+	// it, so it must not blacklist the whole configured coin list (one bad
+	// or expired API key shouldn't take every coin down).
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}, {ID: "ethereum", DisplayName: "Ethereum"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.SetRetryConfig(3, time.Millisecond)
+
+	_, err := service.GetPrices()
+	require.NoError(t, err)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requestCount), "a 404 is permanent and shouldn't be retried")
+	assert.False(t, service.IsCoinUnavailable("bitcoin"))
+	assert.False(t, service.IsCoinUnavailable("ethereum"))
+
+	// Force past the cache so the second call would hit CoinGecko again if
+	// the batch had been (incorrectly) excluded entirely.
+	service.cacheMu.Lock()
+	service.cacheTime = time.Time{}
+	service.cacheMu.Unlock()
+
+	_, err = service.GetPrices()
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&requestCount), "coins shouldn't be excluded from the next request just because of a prior 4xx")
+}
+
+func TestGetPrices_CoinMissingFromResponse_MarksThatCoinUnavailable(t *testing.T) {
+	// CoinGecko reports an unrecognized ID by omitting it from an otherwise-
+	// 200 response, not by erroring the whole batch - that omission is what
+	// should attribute unavailability to the specific coin.
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}, {ID: "bogus-coin", DisplayName: "Bogus"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetPrices()
+
+	require.NoError(t, err)
+	assert.False(t, service.IsCoinUnavailable("bitcoin"))
+	assert.True(t, service.IsCoinUnavailable("bogus-coin"))
+}
+
+func TestGetPrices_AfterCoinMarkedUnavailable_SkipsUpstreamRequestForItAlone(t *testing.T) {
+	var gotIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIDs = strings.Split(r.URL.Query().Get("ids"), ",")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}, {ID: "bogus-coin", DisplayName: "Bogus"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetPrices()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bitcoin", "bogus-coin"}, gotIDs)
+	assert.True(t, service.IsCoinUnavailable("bogus-coin"))
+
+	// Force past the cache so the second call would hit CoinGecko again.
+	service.cacheMu.Lock()
+	service.cacheTime = time.Time{}
+	service.cacheMu.Unlock()
+
+	_, err = service.GetPrices()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bitcoin"}, gotIDs, "an already-unavailable coin shouldn't be requested again")
+}
+
+func TestGetPrices_UnavailableCoin_RetriedAfterRecheckInterval(t *testing.T) {
+	var gotIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotIDs = strings.Split(r.URL.Query().Get("ids"), ",")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}, {ID: "bogus-coin", DisplayName: "Bogus"}}
+	clock := clockwork.NewFakeClock()
+	service := NewServiceWithClock(coins, clock)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetPrices()
+	require.NoError(t, err)
+	assert.True(t, service.IsCoinUnavailable("bogus-coin"))
+
+	clock.Advance(unavailableRecheckInterval + time.Second)
+	service.cacheMu.Lock()
+	service.cacheTime = time.Time{}
+	service.cacheMu.Unlock()
+
+	_, err = service.GetPrices()
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"bitcoin", "bogus-coin"}, gotIDs, "a coin past its recheck interval should be requested again")
+}
+
+func TestSetRetryConfig_NonPositiveValuesFallBackToDefaults(t *testing.T) {
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+
+	service.SetRetryConfig(0, 0)
+
+	assert.Equal(t, defaultRetryAttempts, service.retryAttempts)
+	assert.Equal(t, defaultRetryBaseDelay, service.retryBaseDelay)
+}
+
+func TestNewService_WithoutAPIKey_UsesPublicBaseURL(t *testing.T) {
+	t.Setenv("COINGECKO_API_KEY", "")
+
+	service := NewService([]config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}})
+
+	assert.Equal(t, defaultPingURL, service.pingURL)
+	assert.Empty(t, service.apiKey)
+}
+
+func TestNewService_WithAPIKey_UsesProBaseURL(t *testing.T) {
+	t.Setenv("COINGECKO_API_KEY", "test-key-123")
+
+	service := NewService([]config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}})
+
+	assert.Equal(t, proBaseURL+"/ping", service.pingURL)
+	assert.Equal(t, proBaseURL+"/coins/list", service.catalogURL)
+	assert.Equal(t, proBaseURL+"/simple/price", service.priceURL)
+	assert.Equal(t, "test-key-123", service.apiKey)
+}
+
+func TestGetPrices_WithAPIKey_SendsHeaderOnRequest(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get("x-cg-pro-api-key")
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("COINGECKO_API_KEY", "secret-key")
+	service := NewService([]config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}})
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetPrices()
+
+	require.NoError(t, err)
+	assert.Equal(t, "secret-key", gotHeader)
+}
+
+func TestGetPrices_WithoutAPIKey_NoHeaderSent(t *testing.T) {
+	var gotHeader string
+	sawHeader := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader, sawHeader = r.Header.Get("x-cg-pro-api-key"), r.Header.Get("x-cg-pro-api-key") != ""
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	t.Setenv("COINGECKO_API_KEY", "")
+	service := NewService([]config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}})
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+
+	_, err := service.GetPrices()
+
+	require.NoError(t, err)
+	assert.False(t, sawHeader)
+	assert.Empty(t, gotHeader)
+}
+
+func TestGetPrices_RateLimited_FallsBackAndRecordsDeadline(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.Header().Set("Retry-After", "30")
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.SetRetryConfig(3, time.Millisecond)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	// A 429 is terminal for the current fetch: exactly one request, no retries.
+	assert.Equal(t, int32(1), atomic.LoadInt32(&requestCount))
+	assert.True(t, service.isRateLimited())
+}
+
+func TestGetPrices_WhileRateLimited_SkipsUpstreamFetch(t *testing.T) {
+	var requestCount int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requestCount, 1)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.markRateLimited(time.Minute)
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&requestCount), "upstream should not be hit while rate limited")
+}
+
+func TestGetPrices_AfterRateLimitExpires_FetchesAgain(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"bitcoin": {"usd": 50000.00}}`))
+	}))
+	defer server.Close()
+
+	coins := []config.CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin"}}
+	service := NewService(coins)
+	service.client = server.Client()
+	service.SetBaseURL(server.URL)
+	service.markRateLimited(-time.Second) // already expired
+
+	prices, err := service.GetPrices()
+
+	require.NoError(t, err)
+	require.Len(t, prices, 1)
+	assert.Equal(t, 50000.00, prices[0].Price)
+}
+
+func TestParseRetryAfter_Seconds(t *testing.T) {
+	assert.Equal(t, 30*time.Second, parseRetryAfter("30"))
+}
+
+func TestParseRetryAfter_HTTPDate(t *testing.T) {
+	when := time.Now().Add(45 * time.Second)
+	got := parseRetryAfter(when.UTC().Format(http.TimeFormat))
+	assert.InDelta(t, 45*time.Second, got, float64(2*time.Second))
+}
+
+func TestParseRetryAfter_EmptyOrInvalidFallsBackToDefault(t *testing.T) {
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter(""))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("not-a-valid-value"))
+	assert.Equal(t, defaultRetryAfter, parseRetryAfter("-5"))
+}
+
+func TestJitteredBackoff_GrowsWithAttemptAndStaysNonNegative(t *testing.T) {
+	base := 100 * time.Millisecond
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		delay := jitteredBackoff(base, attempt)
+		assert.GreaterOrEqual(t, delay, time.Duration(0))
+
+		// The un-jittered backoff doubles each attempt; jitter keeps the
+		// delay within +/-50% of it.
+		expected := base * time.Duration(1<<uint(attempt-1))
+		assert.GreaterOrEqual(t, delay, expected/2)
+		assert.Less(t, delay, expected*3/2)
+	}
+}
+
+func TestPortfolioValue_SumsHoldingsAtCurrentPrices(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin", Holdings: 2},
+		{ID: "ethereum", DisplayName: "Ethereum", Holdings: 10},
+		{ID: "dogecoin", DisplayName: "Dogecoin"}, // no holdings, excluded
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{
+		{ID: "bitcoin", Price: 50000},
+		{ID: "ethereum", Price: 3000},
+		{ID: "dogecoin", Price: 0.10},
+	}
+	service.cacheTime = time.Now()
+
+	pv := service.PortfolioValue()
+
+	assert.False(t, pv.Partial)
+	assert.Equal(t, 130000.0, pv.Total)
+	assert.Len(t, pv.Holdings, 2)
+}
+
+func TestPortfolioValue_PartialWhenHeldCoinMissingPrice(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin", Holdings: 2},
+		{ID: "ethereum", DisplayName: "Ethereum", Holdings: 10},
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{
+		{ID: "bitcoin", Price: 50000},
+		// ethereum missing from the priced set
+	}
+	service.cacheTime = time.Now()
+
+	pv := service.PortfolioValue()
+
+	assert.True(t, pv.Partial)
+	assert.Equal(t, 100000.0, pv.Total)
+	assert.Len(t, pv.Holdings, 1)
+}
+
+func TestPortfolioValue_NoHoldingsConfigured(t *testing.T) {
+	coins := []config.CoinConfig{
+		{ID: "bitcoin", DisplayName: "Bitcoin"},
+	}
+
+	service := NewService(coins)
+	service.cache = []Coin{{ID: "bitcoin", Price: 50000}}
+	service.cacheTime = time.Now()
+
+	pv := service.PortfolioValue()
+
+	assert.False(t, pv.Partial)
+	assert.Equal(t, 0.0, pv.Total)
+	assert.Empty(t, pv.Holdings)
 }
@@ -1,15 +1,38 @@
 package config
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
 
 // Config holds all application configuration
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
-	Coins    []CoinConfig   `mapstructure:"coins"`
-	Features FeaturesConfig `mapstructure:"features"`
-	Security SecurityConfig `mapstructure:"security"`
-	Links    LinksConfig    `mapstructure:"links"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Coins     []CoinConfig    `mapstructure:"coins"`
+	Features  FeaturesConfig  `mapstructure:"features"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	Links     LinksConfig     `mapstructure:"links"`
+	Coingecko CoingeckoConfig `mapstructure:"coingecko"`
+}
+
+// CoingeckoConfig holds settings for the CoinGecko client
+type CoingeckoConfig struct {
+	// CatalogCachePath, if set, persists the coins/list catalog cache to
+	// disk so it survives restarts. Empty disables disk persistence.
+	CatalogCachePath string `mapstructure:"catalog_cache_path"`
+	// VsCurrency is the fiat currency prices are quoted in, e.g. "usd" or
+	// "eur". Defaults to "usd".
+	VsCurrency string `mapstructure:"vs_currency"`
+	// StrictVsCurrency makes serve fail at startup if VsCurrency isn't in
+	// CoinGecko's supported_vs_currencies list, instead of just logging a
+	// vs_currency_unsupported warning and continuing (the default), since an
+	// unsupported currency otherwise shows up as a silent wall of zeros.
+	StrictVsCurrency bool `mapstructure:"strict_vs_currency"`
 }
 
 // LinksConfig holds the mandatory feedback link URLs
@@ -20,25 +43,217 @@ type LinksConfig struct {
 
 // ServerConfig holds HTTP server settings
 type ServerConfig struct {
-	Port int    `mapstructure:"port"`
-	Host string `mapstructure:"host"`
+	Port        int               `mapstructure:"port"`
+	Host        string            `mapstructure:"host"`
+	Timeouts    TimeoutsConfig    `mapstructure:"timeouts"`
+	Compression CompressionConfig `mapstructure:"compression"`
+	// BasePath prefixes every route, generated URL, and asset path, for
+	// hosting the app under a reverse-proxy subpath (e.g. "/coinops"). Empty
+	// (default) hosts at the root. Must start with "/" and not end with one.
+	BasePath string `mapstructure:"base_path"`
+	// AssetsDir, if set, serves static files from this directory ahead of
+	// the embedded assets, so operators can override CSS/JS without
+	// rebuilding. Files it doesn't provide still fall back to the embedded
+	// copy. Empty (default) serves only the embedded assets.
+	AssetsDir string `mapstructure:"assets_dir"`
+}
+
+// CompressionConfig controls gzip response compression.
+type CompressionConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// MinSizeBytes is the response size below which compression is skipped,
+	// since gzipping small HTMX fragments costs more CPU than it saves.
+	MinSizeBytes int `mapstructure:"min_size_bytes"`
+}
+
+// TimeoutsConfig controls the http.Server timeouts guarding against
+// slow-loris clients holding connections open indefinitely.
+type TimeoutsConfig struct {
+	// ReadTimeoutMs bounds how long reading the entire request (including
+	// body) may take.
+	ReadTimeoutMs int `mapstructure:"read_timeout_ms"`
+	// ReadHeaderTimeoutMs bounds how long reading request headers may take.
+	ReadHeaderTimeoutMs int `mapstructure:"read_header_timeout_ms"`
+	// WriteTimeoutMs bounds how long writing the response may take. Must
+	// exceed the slowest handler. handleGenerateReport's simulated work runs
+	// in a background job after the response is sent, so it doesn't count
+	// against this.
+	WriteTimeoutMs int `mapstructure:"write_timeout_ms"`
+	// IdleTimeoutMs bounds how long a keep-alive connection may sit idle
+	// between requests.
+	IdleTimeoutMs int `mapstructure:"idle_timeout_ms"`
 }
 
 // LoggingConfig holds logging settings
 type LoggingConfig struct {
 	Level  string `mapstructure:"level"`
 	Format string `mapstructure:"format"`
+	// SlowRequestMs is the request duration above which LoggingMiddleware
+	// emits a slow_request warning in addition to the usual
+	// request_completed log. Zero or unset falls back to 1000ms.
+	SlowRequestMs int `mapstructure:"slow_request_ms"`
+	// SampleRate is the fraction (0.0-1.0) of successful requests to
+	// SampledPaths that LoggingMiddleware actually logs. Requests with a
+	// status >= 400 are always logged regardless. Zero or unset falls back
+	// to 1.0 (log everything).
+	SampleRate float64 `mapstructure:"sample_rate"`
+	// SampledPaths lists path prefixes subject to SampleRate, e.g. "/health"
+	// or "/assets/", so noisy health checks and static assets can be logged
+	// at a reduced rate without affecting everything else.
+	SampledPaths []string `mapstructure:"sampled_paths"`
+	// DebugBodyPaths lists path prefixes, e.g. "/auth", whose request and
+	// response bodies get logged (truncated, credentials redacted) by
+	// DebugBodyLoggingMiddleware. Only takes effect when Level is "debug";
+	// unset means no bodies are ever logged, since this is a targeted aid
+	// for debugging one misbehaving endpoint, not a general body logger.
+	DebugBodyPaths []string `mapstructure:"debug_body_paths"`
 }
 
 // CoinConfig holds cryptocurrency display settings
 type CoinConfig struct {
 	ID          string `mapstructure:"id"`
 	DisplayName string `mapstructure:"display_name"`
+	// RefreshIntervalMs overrides features.avg_refresh_interval_ms for this
+	// coin's Poisson delay queue, so slow-moving coins (e.g. stablecoins)
+	// can refresh less often than volatile ones. Zero or unset falls back
+	// to the global average.
+	RefreshIntervalMs int `mapstructure:"refresh_interval_ms"`
+	// FallbackPrice is used as this coin's price in fallbackPrices when both
+	// the upstream API and the built-in mock data lack it, so a configured
+	// coin never silently disappears from the offline fallback. Zero or
+	// unset falls back to defaultFallbackPrice.
+	FallbackPrice float64 `mapstructure:"fallback_price"`
+	// AlertsDisabled excludes this coin from price/percent alert evaluation,
+	// e.g. for stablecoins whose small moves would otherwise be noise.
+	// False (unset) means alerts are enabled, which keeps existing coin
+	// entries unaffected until they opt out explicitly.
+	AlertsDisabled bool `mapstructure:"alerts_disabled"`
+	// Holdings is how much of this coin the operator holds, used by
+	// Service.PortfolioValue to compute a total portfolio value. Zero or
+	// unset means this coin isn't part of the tracked portfolio.
+	Holdings float64 `mapstructure:"holdings"`
+	// Pinned makes this coin lead the ticker ahead of the active sort,
+	// in config order among other pinned coins, regardless of price/name
+	// sort direction.
+	Pinned bool `mapstructure:"pinned"`
 }
 
 // FeaturesConfig holds feature flags and settings
 type FeaturesConfig struct {
 	AvgRefreshIntervalMs int `mapstructure:"avg_refresh_interval_ms"`
+	// PriceCacheTTLMs controls how long fetched prices are cached before the
+	// coingecko service re-fetches from the API. Zero or unset falls back to
+	// a 30s default; raise it to be gentler on CoinGecko's rate-limited free
+	// tier, or lower it for demos where fresher prices matter more.
+	PriceCacheTTLMs int `mapstructure:"price_cache_ttl_ms"`
+	// CurrencySymbol is shown by the "money" template helper, e.g. "$" or
+	// "€". Empty derives a sensible symbol from coingecko.vs_currency.
+	CurrencySymbol string `mapstructure:"currency_symbol"`
+	// CurrencySymbolPosition is "prefix" or "suffix". Empty derives a
+	// sensible position from coingecko.vs_currency.
+	CurrencySymbolPosition string `mapstructure:"currency_symbol_position"`
+	// MetricsEnabled registers the /metrics endpoint and starts recording
+	// per-route request counts and latency histograms for Prometheus.
+	MetricsEnabled bool `mapstructure:"metrics_enabled"`
+	// TickerStrictUnknownIDs controls how /api/ticker handles an ?ids= value
+	// that isn't in the configured coin list: true 404s the whole request,
+	// false (default) skips the unknown ID and logs a warning.
+	TickerStrictUnknownIDs bool `mapstructure:"ticker_strict_unknown_ids"`
+	// RetryAttempts caps how many times the CoinGecko price fetch is retried
+	// (with exponential backoff) before falling back to synthetic data. Zero
+	// or unset falls back to a default of 3.
+	RetryAttempts int `mapstructure:"retry_attempts"`
+	// RetryBaseDelayMs is the base delay the price fetch's exponential
+	// backoff grows from between retries, before jitter. Zero or unset falls
+	// back to a 200ms default.
+	RetryBaseDelayMs int `mapstructure:"retry_base_delay_ms"`
+	// MarketChartCacheSize caps how many (coin, days) price history entries
+	// GetHistory's LRU keeps warm for the coin detail sparkline at once.
+	// Zero or unset falls back to a default of 20; the least-recently-used
+	// entry is evicted once the cache is full.
+	MarketChartCacheSize int `mapstructure:"market_chart_cache_size"`
+	// NotificationMaxTitle caps a notification's title length; anything
+	// longer is truncated with an ellipsis when added to the store. Zero or
+	// unset falls back to a default of 100.
+	NotificationMaxTitle int `mapstructure:"notification_max_title"`
+	// NotificationMaxMessage caps a notification's message length the same
+	// way NotificationMaxTitle caps its title. Zero or unset falls back to a
+	// default of 500.
+	NotificationMaxMessage int `mapstructure:"notification_max_message"`
+	// MaxNotifications caps how many notifications the store keeps; adding
+	// beyond the cap drops the oldest ones. Zero or unset falls back to a
+	// default of 100, keeping the notifications modal (and the store's
+	// memory) bounded on a long-running server.
+	MaxNotifications int `mapstructure:"max_notifications"`
+	// NotificationsDisplayLimit caps how many notifications handleNotifications
+	// renders into the modal at once, independent of MaxNotifications (the
+	// store's total capacity). Zero or unset means no limit - render
+	// everything the store holds. A caller can still fetch the full list via
+	// GET /notifications?all=true.
+	NotificationsDisplayLimit int `mapstructure:"notifications_display_limit"`
+	// RefreshMode selects how the ticker table keeps its prices current:
+	// "per_coin" (default) has each row poll /ticker/{id} on its own
+	// Poisson-scheduled timer, "full_table" polls the whole /ticker table on
+	// a single timer, and "off" disables auto-refresh entirely.
+	RefreshMode string `mapstructure:"refresh_mode"`
+	// Canary configures per-request feature flag overrides for canary
+	// testing in production - see Server.FeatureEnabled.
+	Canary CanaryConfig `mapstructure:"canary"`
+	// ReportDurationMs is how long the simulated compliance report
+	// generation job takes. Zero or unset falls back to a default of 3000.
+	ReportDurationMs int `mapstructure:"report_duration_ms"`
+	// PprofEnabled registers net/http/pprof's handlers under /debug/pprof/,
+	// requiring the same session auth as the rest of the app (it is
+	// deliberately absent from isPublicEndpoint). Profiles can leak request
+	// data (URLs, header values, in-flight arguments) and let a caller who
+	// reaches the endpoint materially load the server, so leave this false
+	// outside of a trusted operator debugging a live incident.
+	PprofEnabled bool `mapstructure:"pprof_enabled"`
+	// DefaultPageSize is the page_size a paginated endpoint uses when the
+	// client omits the query parameter (or sends one that doesn't parse as
+	// a positive integer). Zero or unset falls back to a default of 25.
+	DefaultPageSize int `mapstructure:"default_page_size"`
+	// MaxPageSize caps the page_size a client can request, regardless of
+	// what it asks for, so a request like ?page_size=100000 can't force a
+	// huge response. Zero or unset falls back to a default of 100.
+	MaxPageSize int `mapstructure:"max_page_size"`
+	// DelayDistribution selects the distribution generateDelayQueue draws
+	// refresh delays from: "exponential" (default) models inter-arrival
+	// times in a Poisson process and has a long tail of occasional large
+	// delays, "poisson" draws a discrete event count via Knuth's algorithm
+	// and stays much closer to the mean. Both are clamped to
+	// DelayMinFactor-DelayMaxFactor of the coin's mean refresh interval.
+	DelayDistribution string `mapstructure:"delay_distribution"`
+	// DelayMinFactor is the lower clamp bound generateDelayQueue applies,
+	// as a multiple of the coin's mean refresh interval. Zero or unset
+	// falls back to math.DefaultMinClampFactor (0.1x).
+	DelayMinFactor float64 `mapstructure:"delay_min_factor"`
+	// DelayMaxFactor is the upper clamp bound generateDelayQueue applies,
+	// as a multiple of the coin's mean refresh interval. Zero or unset
+	// falls back to math.DefaultMaxClampFactor (10x).
+	DelayMaxFactor float64 `mapstructure:"delay_max_factor"`
+	// APIOnly skips template parsing in server.New and disables the HTML
+	// page/HTMX fragment routes (login, the dashboard itself, ticker
+	// fragments, notifications, report generation) and the admin routes,
+	// keeping only the JSON API endpoints (/api/*, /health, /metadata).
+	// For deployments embedding this purely as a price API, this lightens
+	// startup (no template parsing) and shrinks the attack surface (no
+	// session/login flow to secure).
+	APIOnly bool `mapstructure:"api_only"`
+}
+
+// CanaryConfig lets operators enable in-development behavior for a subset
+// of requests without a global config flip.
+type CanaryConfig struct {
+	// Flags maps a feature flag name to its default state. A name absent
+	// from this map defaults to disabled.
+	Flags map[string]bool `mapstructure:"flags"`
+	// TrustedCIDRs lists client IP ranges allowed to override a flag via an
+	// "X-Feature-<Name>: on"/"off" header (see Server.FeatureEnabled). A
+	// request carrying a valid session is trusted regardless of IP. Empty
+	// means no IP is trusted by address alone - only an authenticated
+	// session can override.
+	TrustedCIDRs []string `mapstructure:"trusted_cidrs"`
 }
 
 // SecurityConfig holds security-related settings
@@ -46,18 +261,135 @@ type FeaturesConfig struct {
 type SecurityConfig struct {
 	BasicAuth   BasicAuthConfig   `mapstructure:"basic_auth"`
 	IPAllowlist IPAllowlistConfig `mapstructure:"ip_allowlist"`
+	Session     SessionConfig     `mapstructure:"session"`
+	RateLimit   RateLimitConfig   `mapstructure:"rate_limit"`
+	// ForceHTTPS redirects plain-HTTP requests to HTTPS, honoring
+	// X-Forwarded-Proto when TLS is terminated by a trusted proxy.
+	ForceHTTPS bool `mapstructure:"force_https"`
+	// TrustedProxies lists CIDR ranges of reverse proxies allowed to set
+	// X-Forwarded-For/X-Real-IP. Forwarded headers are only honored when
+	// the immediate peer (r.RemoteAddr) falls within one of these ranges;
+	// otherwise the connection's own address is used, so a direct client
+	// can't spoof its IP to bypass the IP allowlist or skew access logs.
+	// Empty (the default) trusts no proxy and always uses RemoteAddr.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
+	// TrustForwardedChain changes how getClientIP resolves a client IP once
+	// a request comes through a trusted proxy: instead of taking the
+	// leftmost X-Forwarded-For entry (which a client can freely forge by
+	// prepending their own IP before it ever reaches the proxy), it walks
+	// the list right-to-left and returns the first entry that isn't itself
+	// a trusted proxy - the correct client IP behind a known chain of
+	// proxies. Defaults to false (leftmost) to avoid changing behavior for
+	// existing deployments; only enable it once TrustedProxies covers every
+	// proxy hop the request passes through.
+	TrustForwardedChain bool `mapstructure:"trust_forwarded_chain"`
+	// SecurityHeaders controls the baseline response headers set by
+	// SecurityHeadersMiddleware (X-Content-Type-Options, X-Frame-Options,
+	// Content-Security-Policy, Strict-Transport-Security).
+	SecurityHeaders SecurityHeadersConfig `mapstructure:"security_headers"`
+	// CORS controls cross-origin access to the JSON API endpoints (see
+	// CORSMiddleware). It never applies to HTML pages, so it can't be used
+	// to weaken the session-cookie auth flow.
+	CORS CORSConfig `mapstructure:"cors"`
+	// AllowedRedirects restricts the post-login "redirect" query parameter
+	// to this exact set of paths (e.g. "/", "/ticker", "/reports"), falling
+	// back to "/" for anything else. Empty (the default) allows any local
+	// path instead, per isLocalRedirectPath.
+	AllowedRedirects []string `mapstructure:"allowed_redirects"`
+}
+
+// CORSConfig configures CORSMiddleware.
+type CORSConfig struct {
+	// AllowedOrigins lists origins (e.g. "https://admin.example.com")
+	// allowed to make cross-origin requests to the API endpoints.
+	// Access-Control-Allow-Origin echoes back the request's Origin header
+	// when it matches one of these, rather than sending "*", so a matching
+	// origin is required even though credentials aren't part of this flow.
+	// Empty (the default) sends no CORS headers, blocking all cross-origin
+	// API access as before.
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+}
+
+// SecurityHeadersConfig controls SecurityHeadersMiddleware.
+type SecurityHeadersConfig struct {
+	// Enabled turns the middleware on. Defaults to true; the headers it
+	// sets are safe for essentially any deployment, including this app's
+	// Alpine/HTMX inline scripts under the default nonce-based policy.
+	Enabled bool `mapstructure:"enabled"`
+	// ContentSecurityPolicy overrides the default Content-Security-Policy
+	// ("script-src 'nonce-{{nonce}}'"). The literal token "{{nonce}}" is
+	// replaced with the request's CSP nonce wherever it appears; operators
+	// who drop the token are opting out of nonce-based script-src and are
+	// responsible for keeping the app's inline scripts working some other
+	// way (e.g. 'unsafe-inline' or hashes). Empty uses the default.
+	ContentSecurityPolicy string `mapstructure:"content_security_policy"`
+}
+
+// RateLimitConfig controls the per-client-IP token-bucket rate limiter.
+type RateLimitConfig struct {
+	Enabled bool `mapstructure:"enabled"`
+	// RequestsPerSecond is the steady-state rate at which a client's bucket
+	// refills.
+	RequestsPerSecond float64 `mapstructure:"requests_per_second"`
+	// Burst is the bucket's capacity, i.e. how many requests a client can
+	// make in a quick burst before being throttled to RequestsPerSecond.
+	Burst int `mapstructure:"burst"`
+	// AuthRequestsPerSecond, if set, gives /auth its own stricter bucket to
+	// slow brute-force login attempts. Zero falls back to the general
+	// RequestsPerSecond/Burst bucket.
+	AuthRequestsPerSecond float64 `mapstructure:"auth_requests_per_second"`
+	AuthBurst             int     `mapstructure:"auth_burst"`
+}
+
+// SessionConfig controls per-user session limits
+type SessionConfig struct {
+	// MaxPerUser caps the number of concurrent sessions a single user may
+	// hold. Zero disables the cap.
+	MaxPerUser int `mapstructure:"max_per_user"`
+	// OnLimit selects what happens when a user is at the cap: "evict_oldest"
+	// (default) or "reject".
+	OnLimit string `mapstructure:"on_limit"`
+	// HostPrefixCookie names the session cookie "__Host-coinops_session"
+	// instead of "coinops_session" on requests served over HTTPS, the
+	// strictest cookie-hardening prefix browsers support (it also requires
+	// Secure, Path=/, and no Domain). Requires security.force_https, since
+	// __Host- cookies are silently dropped by the browser unless the site is
+	// actually served over HTTPS.
+	HostPrefixCookie bool `mapstructure:"host_prefix_cookie"`
+	// TimeoutMs is how long a session remains valid after creation. Zero or
+	// unset falls back to 24 hours.
+	TimeoutMs int `mapstructure:"timeout_ms"`
+	// SlidingExpiration extends a session's expiry by TimeoutMs whenever it's
+	// used close to expiring, instead of expiring exactly TimeoutMs after
+	// login. Disabled by default, so deployments that need a strict fixed
+	// expiry keep today's behavior.
+	SlidingExpiration bool `mapstructure:"sliding_expiration"`
+	// ClockSkewToleranceMs widens the expiry check by this many
+	// milliseconds before a session is treated as expired, absorbing small
+	// clock differences between the host that created the session and the
+	// host checking it. Matters once sessions are persisted and loaded
+	// across restarts/hosts rather than always compared against the clock
+	// that wrote them. Zero (the default) applies no tolerance.
+	ClockSkewToleranceMs int `mapstructure:"clock_skew_tolerance_ms"`
 }
 
 // BasicAuthConfig controls HTTP Basic Authentication
 // Username/password come from BASIC_AUTH_USERNAME and BASIC_AUTH_PASSWORD env vars
 type BasicAuthConfig struct {
 	Enabled bool `mapstructure:"enabled"`
+	// BcryptCost is the target bcrypt cost. Hashes stored below this cost
+	// (e.g. after raising it) are flagged so an operator can regenerate them.
+	BcryptCost int `mapstructure:"bcrypt_cost"`
 }
 
 // IPAllowlistConfig controls IP-based access restrictions
 type IPAllowlistConfig struct {
 	Enabled bool     `mapstructure:"enabled"`
 	CIDRs   []string `mapstructure:"cidrs"`
+	// Mode selects how CIDRs is interpreted: "allow" (default) permits only
+	// matching IPs and blocks everything else; "deny" blocks matching IPs
+	// and permits everything else.
+	Mode string `mapstructure:"mode"`
 }
 
 // DefaultConfig returns the default configuration
@@ -66,6 +398,16 @@ func DefaultConfig() *Config {
 		Server: ServerConfig{
 			Port: 3000,
 			Host: "0.0.0.0",
+			Timeouts: TimeoutsConfig{
+				ReadTimeoutMs:       5000,
+				ReadHeaderTimeoutMs: 5000,
+				WriteTimeoutMs:      10000,
+				IdleTimeoutMs:       120000,
+			},
+			Compression: CompressionConfig{
+				Enabled:      true,
+				MinSizeBytes: 1024,
+			},
 		},
 		Logging: LoggingConfig{
 			Level:  "info",
@@ -80,13 +422,21 @@ func DefaultConfig() *Config {
 		},
 		Features: FeaturesConfig{
 			AvgRefreshIntervalMs: 5000,
+			PriceCacheTTLMs:      30000,
+			RefreshMode:          "per_coin",
 		},
 		Security: SecurityConfig{
 			BasicAuth: BasicAuthConfig{
-				Enabled: false,
+				Enabled:    false,
+				BcryptCost: bcrypt.DefaultCost,
+			},
+			Session: SessionConfig{
+				MaxPerUser: 0,
+				OnLimit:    "evict_oldest",
 			},
 			IPAllowlist: IPAllowlistConfig{
 				Enabled: false,
+				Mode:    "allow",
 				CIDRs: []string{
 					// IPv4 private ranges
 					"127.0.0.0/8",    // Loopback
@@ -99,21 +449,195 @@ func DefaultConfig() *Config {
 					"fe80::/10", // Link-local addresses
 				},
 			},
+			SecurityHeaders: SecurityHeadersConfig{
+				Enabled: true,
+			},
 		},
 		Links: LinksConfig{
 			RequestFeatureURL: "https://github.com/hiAndrewQuinn/pahg-template/issues/new?labels=enhancement&title=%5BFeature%5D+",
 			ReportBugURL:      "https://github.com/hiAndrewQuinn/pahg-template/issues/new?labels=bug&title=%5BBug%5D+",
 		},
+		Coingecko: CoingeckoConfig{
+			CatalogCachePath: "",
+			VsCurrency:       "usd",
+		},
 	}
 }
 
-// Validate checks that all mandatory configuration fields are set
+// Validate checks that all mandatory configuration fields are set and sane.
+// It collects every problem it finds rather than stopping at the first one,
+// so a broken config reports everything wrong with it in a single pass
+// instead of forcing a fix-rerun-fix cycle to discover them one at a time.
 func (c *Config) Validate() error {
+	var errs []error
+
 	if c.Links.RequestFeatureURL == "" {
-		return fmt.Errorf("links.request_feature_url is required")
+		errs = append(errs, fmt.Errorf("links.request_feature_url is required"))
 	}
 	if c.Links.ReportBugURL == "" {
-		return fmt.Errorf("links.report_bug_url is required")
+		errs = append(errs, fmt.Errorf("links.report_bug_url is required"))
+	}
+	if c.Security.Session.MaxPerUser < 0 {
+		errs = append(errs, fmt.Errorf("security.session.max_per_user must not be negative"))
+	}
+	if c.Security.Session.TimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("security.session.timeout_ms must not be negative"))
+	}
+	if c.Security.Session.ClockSkewToleranceMs < 0 {
+		errs = append(errs, fmt.Errorf("security.session.clock_skew_tolerance_ms must not be negative"))
+	}
+	if c.Server.Timeouts.ReadTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("server.timeouts.read_timeout_ms must not be negative"))
+	}
+	if c.Server.Timeouts.ReadHeaderTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("server.timeouts.read_header_timeout_ms must not be negative"))
+	}
+	if c.Server.Timeouts.WriteTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("server.timeouts.write_timeout_ms must not be negative"))
+	}
+	if c.Server.Timeouts.IdleTimeoutMs < 0 {
+		errs = append(errs, fmt.Errorf("server.timeouts.idle_timeout_ms must not be negative"))
+	}
+	if c.Server.Compression.MinSizeBytes < 0 {
+		errs = append(errs, fmt.Errorf("server.compression.min_size_bytes must not be negative"))
+	}
+	if c.Logging.SlowRequestMs < 0 {
+		errs = append(errs, fmt.Errorf("logging.slow_request_ms must not be negative"))
+	}
+	if c.Logging.SampleRate < 0 || c.Logging.SampleRate > 1 {
+		errs = append(errs, fmt.Errorf("logging.sample_rate must be between 0 and 1"))
+	}
+	if c.Features.RetryAttempts < 0 {
+		errs = append(errs, fmt.Errorf("features.retry_attempts must not be negative"))
+	}
+	if c.Features.RetryBaseDelayMs < 0 {
+		errs = append(errs, fmt.Errorf("features.retry_base_delay_ms must not be negative"))
+	}
+	if c.Features.MarketChartCacheSize < 0 {
+		errs = append(errs, fmt.Errorf("features.market_chart_cache_size must not be negative"))
+	}
+	if c.Features.NotificationMaxTitle < 0 {
+		errs = append(errs, fmt.Errorf("features.notification_max_title must not be negative"))
+	}
+	if c.Features.NotificationMaxMessage < 0 {
+		errs = append(errs, fmt.Errorf("features.notification_max_message must not be negative"))
+	}
+	if c.Features.MaxNotifications < 0 {
+		errs = append(errs, fmt.Errorf("features.max_notifications must not be negative"))
+	}
+	if c.Features.NotificationsDisplayLimit < 0 {
+		errs = append(errs, fmt.Errorf("features.notifications_display_limit must not be negative"))
+	}
+	if c.Features.ReportDurationMs < 0 {
+		errs = append(errs, fmt.Errorf("features.report_duration_ms must not be negative"))
+	}
+	if c.Features.DefaultPageSize < 0 {
+		errs = append(errs, fmt.Errorf("features.default_page_size must not be negative"))
+	}
+	if c.Features.MaxPageSize < 0 {
+		errs = append(errs, fmt.Errorf("features.max_page_size must not be negative"))
+	}
+	for _, coin := range c.Coins {
+		if coin.RefreshIntervalMs < 0 {
+			errs = append(errs, fmt.Errorf("coins[%q].refresh_interval_ms must not be negative", coin.ID))
+		}
+		if coin.FallbackPrice < 0 {
+			errs = append(errs, fmt.Errorf("coins[%q].fallback_price must not be negative", coin.ID))
+		}
+	}
+	if c.Server.BasePath != "" {
+		if !strings.HasPrefix(c.Server.BasePath, "/") {
+			errs = append(errs, fmt.Errorf("server.base_path must start with \"/\""))
+		}
+		if strings.HasSuffix(c.Server.BasePath, "/") {
+			errs = append(errs, fmt.Errorf("server.base_path must not end with \"/\""))
+		}
+	}
+	switch c.Security.Session.OnLimit {
+	case "", "evict_oldest", "reject":
+	default:
+		errs = append(errs, fmt.Errorf("security.session.on_limit must be %q or %q", "evict_oldest", "reject"))
+	}
+	switch c.Security.IPAllowlist.Mode {
+	case "", "allow", "deny":
+	default:
+		errs = append(errs, fmt.Errorf("security.ip_allowlist.mode must be %q or %q", "allow", "deny"))
+	}
+	switch c.Features.RefreshMode {
+	case "", "per_coin", "full_table", "off":
+	default:
+		errs = append(errs, fmt.Errorf("features.refresh_mode must be %q, %q, or %q", "per_coin", "full_table", "off"))
+	}
+	switch c.Features.CurrencySymbolPosition {
+	case "", "prefix", "suffix":
+	default:
+		errs = append(errs, fmt.Errorf("features.currency_symbol_position must be %q or %q", "prefix", "suffix"))
+	}
+	switch c.Features.DelayDistribution {
+	case "", "exponential", "poisson":
+	default:
+		errs = append(errs, fmt.Errorf("features.delay_distribution must be %q or %q", "exponential", "poisson"))
+	}
+	if c.Features.DelayMinFactor < 0 {
+		errs = append(errs, fmt.Errorf("features.delay_min_factor must not be negative"))
+	}
+	if c.Features.DelayMaxFactor < 0 {
+		errs = append(errs, fmt.Errorf("features.delay_max_factor must not be negative"))
+	}
+	if c.Features.DelayMinFactor > 0 && c.Features.DelayMaxFactor > 0 && c.Features.DelayMinFactor >= c.Features.DelayMaxFactor {
+		errs = append(errs, fmt.Errorf("features.delay_min_factor must be less than features.delay_max_factor"))
+	}
+	if c.Security.Session.HostPrefixCookie && !c.Security.ForceHTTPS {
+		errs = append(errs, fmt.Errorf("security.session.host_prefix_cookie requires security.force_https"))
+	}
+	if c.Security.RateLimit.Enabled {
+		if c.Security.RateLimit.RequestsPerSecond <= 0 {
+			errs = append(errs, fmt.Errorf("security.rate_limit.requests_per_second must be positive when enabled"))
+		}
+		if c.Security.RateLimit.Burst <= 0 {
+			errs = append(errs, fmt.Errorf("security.rate_limit.burst must be positive when enabled"))
+		}
+		if c.Security.RateLimit.AuthRequestsPerSecond > 0 && c.Security.RateLimit.AuthBurst <= 0 {
+			errs = append(errs, fmt.Errorf("security.rate_limit.auth_burst must be positive when auth_requests_per_second is set"))
+		}
+	}
+	if c.Server.Port < 1 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535"))
+	}
+	if c.Server.Host == "" {
+		errs = append(errs, fmt.Errorf("server.host is required"))
+	}
+	switch strings.ToLower(c.Logging.Level) {
+	case "debug", "info", "warn", "error":
+	default:
+		errs = append(errs, fmt.Errorf("logging.level must be one of debug, info, warn, error"))
+	}
+	switch strings.ToLower(c.Logging.Format) {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("logging.format must be json or text"))
+	}
+	if c.Features.AvgRefreshIntervalMs <= 0 {
+		errs = append(errs, fmt.Errorf("features.avg_refresh_interval_ms must be positive"))
+	}
+	if len(c.Coins) == 0 {
+		errs = append(errs, fmt.Errorf("coins must contain at least one entry"))
+	}
+	seenCoinIDs := make(map[string]bool, len(c.Coins))
+	for _, coin := range c.Coins {
+		if seenCoinIDs[coin.ID] {
+			errs = append(errs, fmt.Errorf("coins[%q] is a duplicate id", coin.ID))
+		}
+		seenCoinIDs[coin.ID] = true
+	}
+	for _, cidr := range c.Security.IPAllowlist.CIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			errs = append(errs, fmt.Errorf("security.ip_allowlist.cidrs: invalid CIDR %q: %w", cidr, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return errors.Join(errs...)
 	}
 	return nil
 }
@@ -5,6 +5,7 @@ import (
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
 )
 
 func TestDefaultConfig(t *testing.T) {
@@ -15,6 +16,13 @@ func TestDefaultConfig(t *testing.T) {
 	t.Run("server defaults", func(t *testing.T) {
 		assert.Equal(t, 3000, cfg.Server.Port)
 		assert.Equal(t, "0.0.0.0", cfg.Server.Host)
+		assert.Equal(t, 5000, cfg.Server.Timeouts.ReadTimeoutMs)
+		assert.Equal(t, 5000, cfg.Server.Timeouts.ReadHeaderTimeoutMs)
+		assert.Equal(t, 10000, cfg.Server.Timeouts.WriteTimeoutMs)
+		assert.Equal(t, 120000, cfg.Server.Timeouts.IdleTimeoutMs)
+		assert.True(t, cfg.Server.Compression.Enabled)
+		assert.Equal(t, 1024, cfg.Server.Compression.MinSizeBytes)
+		assert.Equal(t, "", cfg.Server.BasePath)
 	})
 
 	t.Run("logging defaults", func(t *testing.T) {
@@ -44,10 +52,24 @@ func TestDefaultConfig(t *testing.T) {
 
 	t.Run("features defaults", func(t *testing.T) {
 		assert.Equal(t, 5000, cfg.Features.AvgRefreshIntervalMs)
+		assert.Equal(t, 30000, cfg.Features.PriceCacheTTLMs)
+		assert.Equal(t, "", cfg.Features.CurrencySymbol)
+		assert.Equal(t, "", cfg.Features.CurrencySymbolPosition)
+		assert.False(t, cfg.Features.MetricsEnabled)
+		assert.False(t, cfg.Features.TickerStrictUnknownIDs)
+	})
+
+	t.Run("rate limit defaults", func(t *testing.T) {
+		assert.False(t, cfg.Security.RateLimit.Enabled)
+		assert.Equal(t, float64(0), cfg.Security.RateLimit.RequestsPerSecond)
+		assert.Equal(t, 0, cfg.Security.RateLimit.Burst)
+		assert.Equal(t, float64(0), cfg.Security.RateLimit.AuthRequestsPerSecond)
+		assert.Equal(t, 0, cfg.Security.RateLimit.AuthBurst)
 	})
 
 	t.Run("security defaults", func(t *testing.T) {
 		assert.False(t, cfg.Security.BasicAuth.Enabled)
+		assert.Equal(t, bcrypt.DefaultCost, cfg.Security.BasicAuth.BcryptCost)
 		assert.False(t, cfg.Security.IPAllowlist.Enabled)
 
 		// Should have default CIDR ranges
@@ -61,6 +83,16 @@ func TestDefaultConfig(t *testing.T) {
 			"fe80::/10",
 		}
 		assert.Equal(t, expectedCIDRs, cfg.Security.IPAllowlist.CIDRs)
+
+		assert.True(t, cfg.Security.SecurityHeaders.Enabled)
+		assert.Equal(t, "", cfg.Security.SecurityHeaders.ContentSecurityPolicy)
+
+		assert.Empty(t, cfg.Security.CORS.AllowedOrigins)
+	})
+
+	t.Run("coingecko defaults", func(t *testing.T) {
+		assert.Equal(t, "", cfg.Coingecko.CatalogCachePath)
+		assert.Equal(t, "usd", cfg.Coingecko.VsCurrency)
 	})
 
 	t.Run("links defaults", func(t *testing.T) {
@@ -96,15 +128,15 @@ func TestConfig_Validate(t *testing.T) {
 		assert.Contains(t, err.Error(), "report_bug_url is required")
 	})
 
-	t.Run("both URLs empty fails on first one", func(t *testing.T) {
+	t.Run("both URLs empty reports both problems", func(t *testing.T) {
 		cfg := DefaultConfig()
 		cfg.Links.RequestFeatureURL = ""
 		cfg.Links.ReportBugURL = ""
 
 		err := cfg.Validate()
 		assert.Error(t, err)
-		// Should fail on the first check (request_feature_url)
 		assert.Contains(t, err.Error(), "request_feature_url is required")
+		assert.Contains(t, err.Error(), "report_bug_url is required")
 	})
 
 	t.Run("custom URLs pass validation", func(t *testing.T) {
@@ -115,6 +147,502 @@ func TestConfig_Validate(t *testing.T) {
 		err := cfg.Validate()
 		assert.NoError(t, err)
 	})
+
+	t.Run("negative max_per_user fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.Session.MaxPerUser = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "max_per_user must not be negative")
+	})
+
+	t.Run("negative clock_skew_tolerance_ms fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.Session.ClockSkewToleranceMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "clock_skew_tolerance_ms must not be negative")
+	})
+
+	t.Run("negative slow_request_ms fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Logging.SlowRequestMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "slow_request_ms must not be negative")
+	})
+
+	t.Run("negative retry_attempts fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.RetryAttempts = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "retry_attempts must not be negative")
+	})
+
+	t.Run("negative retry_base_delay_ms fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.RetryBaseDelayMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "retry_base_delay_ms must not be negative")
+	})
+
+	t.Run("negative market_chart_cache_size fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.MarketChartCacheSize = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "market_chart_cache_size must not be negative")
+	})
+
+	t.Run("negative notification_max_title fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.NotificationMaxTitle = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "notification_max_title must not be negative")
+	})
+
+	t.Run("negative notification_max_message fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.NotificationMaxMessage = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "notification_max_message must not be negative")
+	})
+
+	t.Run("negative max_notifications fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.MaxNotifications = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "max_notifications must not be negative")
+	})
+
+	t.Run("negative notifications_display_limit fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.NotificationsDisplayLimit = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "notifications_display_limit must not be negative")
+	})
+
+	t.Run("invalid delay_distribution fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.DelayDistribution = "gaussian"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "delay_distribution must be")
+	})
+
+	t.Run("negative delay_min_factor fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.DelayMinFactor = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "delay_min_factor must not be negative")
+	})
+
+	t.Run("negative delay_max_factor fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.DelayMaxFactor = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "delay_max_factor must not be negative")
+	})
+
+	t.Run("delay_min_factor not less than delay_max_factor fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.DelayMinFactor = 2
+		cfg.Features.DelayMaxFactor = 1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "delay_min_factor must be less than features.delay_max_factor")
+	})
+
+	t.Run("negative default_page_size fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.DefaultPageSize = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "default_page_size must not be negative")
+	})
+
+	t.Run("negative max_page_size fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.MaxPageSize = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "max_page_size must not be negative")
+	})
+
+	t.Run("negative sample_rate fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Logging.SampleRate = -0.1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "sample_rate must be between 0 and 1")
+	})
+
+	t.Run("sample_rate above 1 fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Logging.SampleRate = 1.1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "sample_rate must be between 0 and 1")
+	})
+
+	t.Run("negative coin refresh_interval_ms fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Coins = []CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin", RefreshIntervalMs: -1}}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "refresh_interval_ms must not be negative")
+	})
+
+	t.Run("negative coin fallback_price fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Coins = []CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin", FallbackPrice: -1}}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "fallback_price must not be negative")
+	})
+
+	t.Run("negative session timeout fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.Session.TimeoutMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "timeout_ms must not be negative")
+	})
+
+	t.Run("invalid on_limit fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.Session.OnLimit = "explode"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "on_limit must be")
+	})
+
+	t.Run("reject on_limit passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.Session.OnLimit = "reject"
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid ip_allowlist mode fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.IPAllowlist.Mode = "explode"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "ip_allowlist.mode must be")
+	})
+
+	t.Run("deny ip_allowlist mode passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.IPAllowlist.Mode = "deny"
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("invalid refresh_mode fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.RefreshMode = "explode"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "refresh_mode must be")
+	})
+
+	t.Run("full_table refresh_mode passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.RefreshMode = "full_table"
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("negative read timeout fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Timeouts.ReadTimeoutMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "read_timeout_ms must not be negative")
+	})
+
+	t.Run("negative read header timeout fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Timeouts.ReadHeaderTimeoutMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "read_header_timeout_ms must not be negative")
+	})
+
+	t.Run("negative write timeout fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Timeouts.WriteTimeoutMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "write_timeout_ms must not be negative")
+	})
+
+	t.Run("negative idle timeout fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Timeouts.IdleTimeoutMs = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "idle_timeout_ms must not be negative")
+	})
+
+	t.Run("negative compression min size fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Compression.MinSizeBytes = -1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "min_size_bytes must not be negative")
+	})
+
+	t.Run("invalid currency symbol position fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.CurrencySymbolPosition = "middle"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "currency_symbol_position must be")
+	})
+
+	t.Run("suffix currency symbol position passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.CurrencySymbolPosition = "suffix"
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("host prefix cookie without force_https fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.Session.HostPrefixCookie = true
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "host_prefix_cookie requires")
+	})
+
+	t.Run("host prefix cookie with force_https passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.ForceHTTPS = true
+		cfg.Security.Session.HostPrefixCookie = true
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("rate limit enabled with zero requests_per_second fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.RateLimit.Enabled = true
+		cfg.Security.RateLimit.Burst = 10
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "requests_per_second must be positive")
+	})
+
+	t.Run("rate limit enabled with zero burst fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.RateLimit.Enabled = true
+		cfg.Security.RateLimit.RequestsPerSecond = 5
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "burst must be positive")
+	})
+
+	t.Run("rate limit with auth rate set but no auth burst fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.RateLimit.Enabled = true
+		cfg.Security.RateLimit.RequestsPerSecond = 5
+		cfg.Security.RateLimit.Burst = 10
+		cfg.Security.RateLimit.AuthRequestsPerSecond = 1
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "auth_burst must be positive")
+	})
+
+	t.Run("base_path without leading slash fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.BasePath = "coinops"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "base_path must start with")
+	})
+
+	t.Run("base_path with trailing slash fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.BasePath = "/coinops/"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "base_path must not end with")
+	})
+
+	t.Run("valid base_path passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.BasePath = "/coinops"
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("rate limit fully configured passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.RateLimit.Enabled = true
+		cfg.Security.RateLimit.RequestsPerSecond = 5
+		cfg.Security.RateLimit.Burst = 10
+		cfg.Security.RateLimit.AuthRequestsPerSecond = 1
+		cfg.Security.RateLimit.AuthBurst = 3
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("port zero fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Port = 0
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "server.port must be between 1 and 65535")
+	})
+
+	t.Run("port above 65535 fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Port = 70000
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "server.port must be between 1 and 65535")
+	})
+
+	t.Run("empty host fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Host = ""
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "server.host is required")
+	})
+
+	t.Run("unknown log level fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Logging.Level = "trace"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "logging.level must be one of debug, info, warn, error")
+	})
+
+	t.Run("unknown log format fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Logging.Format = "xml"
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "logging.format must be json or text")
+	})
+
+	t.Run("zero avg_refresh_interval_ms fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Features.AvgRefreshIntervalMs = 0
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "features.avg_refresh_interval_ms must be positive")
+	})
+
+	t.Run("empty coins list fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Coins = nil
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "coins must contain at least one entry")
+	})
+
+	t.Run("duplicate coin ids fail", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Coins = []CoinConfig{
+			{ID: "bitcoin", DisplayName: "Bitcoin"},
+			{ID: "bitcoin", DisplayName: "Bitcoin Again"},
+		}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), `coins["bitcoin"] is a duplicate id`)
+	})
+
+	t.Run("invalid allowlist CIDR fails", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.IPAllowlist.CIDRs = []string{"not-a-cidr"}
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "security.ip_allowlist.cidrs: invalid CIDR")
+	})
+
+	t.Run("valid allowlist CIDR passes", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Security.IPAllowlist.CIDRs = []string{"10.0.0.0/8"}
+
+		err := cfg.Validate()
+		assert.NoError(t, err)
+	})
+
+	t.Run("multiple problems are all reported together", func(t *testing.T) {
+		cfg := DefaultConfig()
+		cfg.Server.Port = 0
+		cfg.Logging.Level = "trace"
+		cfg.Coins = nil
+
+		err := cfg.Validate()
+		assert.Error(t, err)
+		assert.Contains(t, err.Error(), "server.port must be between 1 and 65535")
+		assert.Contains(t, err.Error(), "logging.level must be one of debug, info, warn, error")
+		assert.Contains(t, err.Error(), "coins must contain at least one entry")
+	})
 }
 
 func TestCoinConfig(t *testing.T) {
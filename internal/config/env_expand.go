@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+)
+
+// ExpandEnv walks cfg's string fields - including inside nested structs and
+// string slices, e.g. Coins[].DisplayName or Security.IPAllowlist.CIDRs -
+// and substitutes ${VAR} references with the corresponding environment
+// variable, the same way os.Expand handles $VAR and ${VAR}. This lets a
+// multi-region deploy reference things like the CoinGecko catalog cache
+// path or a coin's display name as "${REGION}" in the config file instead of
+// templating the YAML externally. A literal dollar sign is written as
+// "$$", matching os.Expand's own treatment of "$$" as the shell-special
+// variable "$".
+//
+// strict controls what happens when a referenced variable is unset: false
+// expands it to "" (os.Expand's default), true makes ExpandEnv return an
+// error naming every unset variable it found instead, so a missing env var
+// fails loudly at startup rather than silently blanking a config value.
+func ExpandEnv(cfg *Config, strict bool) error {
+	var missing []string
+	seen := make(map[string]bool)
+
+	mapping := func(name string) string {
+		if name == "$" {
+			return "$"
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			return value
+		}
+		if !seen[name] {
+			seen[name] = true
+			missing = append(missing, name)
+		}
+		return ""
+	}
+
+	expandStrings(reflect.ValueOf(cfg).Elem(), mapping)
+
+	if strict && len(missing) > 0 {
+		return fmt.Errorf("config references unset environment variable(s): %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// expandStrings recursively walks v, replacing every settable string field
+// or slice element it finds with its os.Expand-substituted form via mapping.
+func expandStrings(v reflect.Value, mapping func(string) string) {
+	switch v.Kind() {
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			expandStrings(v.Field(i), mapping)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			expandStrings(v.Index(i), mapping)
+		}
+	case reflect.String:
+		if v.CanSet() {
+			v.SetString(os.Expand(v.String(), mapping))
+		}
+	}
+}
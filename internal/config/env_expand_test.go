@@ -0,0 +1,71 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEnv_SubstitutesReferencedVariable(t *testing.T) {
+	t.Setenv("COINOPS_TEST_REGION", "eu-west-1")
+
+	cfg := DefaultConfig()
+	cfg.Coingecko.VsCurrency = "usd-${COINOPS_TEST_REGION}"
+	cfg.Coins = []CoinConfig{{ID: "bitcoin", DisplayName: "Bitcoin (${COINOPS_TEST_REGION})"}}
+
+	err := ExpandEnv(cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, "usd-eu-west-1", cfg.Coingecko.VsCurrency)
+	assert.Equal(t, "Bitcoin (eu-west-1)", cfg.Coins[0].DisplayName)
+}
+
+func TestExpandEnv_DollarDollarIsLiteralDollar(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Coingecko.VsCurrency = "price$$"
+
+	err := ExpandEnv(cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, "price$", cfg.Coingecko.VsCurrency)
+}
+
+func TestExpandEnv_UnsetVariableExpandsToEmptyWhenNotStrict(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Coingecko.VsCurrency = "usd-${COINOPS_TEST_DEFINITELY_UNSET}"
+
+	err := ExpandEnv(cfg, false)
+	require.NoError(t, err)
+	assert.Equal(t, "usd-", cfg.Coingecko.VsCurrency)
+}
+
+func TestExpandEnv_UnsetVariableFailsWhenStrict(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Coingecko.VsCurrency = "usd-${COINOPS_TEST_DEFINITELY_UNSET}"
+
+	err := ExpandEnv(cfg, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "COINOPS_TEST_DEFINITELY_UNSET")
+}
+
+func TestExpandEnv_StrictReportsEveryMissingVariableOnce(t *testing.T) {
+	cfg := DefaultConfig()
+	cfg.Coingecko.VsCurrency = "${MISSING_A}"
+	cfg.Coins = []CoinConfig{
+		{ID: "bitcoin", DisplayName: "${MISSING_A}"},
+		{ID: "ethereum", DisplayName: "${MISSING_B}"},
+	}
+
+	err := ExpandEnv(cfg, true)
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "MISSING_A")
+	assert.Contains(t, err.Error(), "MISSING_B")
+}
+
+func TestExpandEnv_NoReferencesIsANoOp(t *testing.T) {
+	cfg := DefaultConfig()
+	before := cfg.Coingecko.VsCurrency
+
+	err := ExpandEnv(cfg, true)
+	require.NoError(t, err)
+	assert.Equal(t, before, cfg.Coingecko.VsCurrency)
+}
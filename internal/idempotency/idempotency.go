@@ -0,0 +1,100 @@
+// Package idempotency provides a thread-safe TTL map for deduplicating
+// retried requests that carry the same idempotency key.
+package idempotency
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// entry pairs a cached value with when it expires.
+type entry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Store caches a value per key for a fixed TTL, so a caller can look up
+// whether a given key was already handled recently and, if so, reuse the
+// cached result instead of repeating the work.
+type Store struct {
+	mu      sync.Mutex
+	clock   clockwork.Clock
+	ttl     time.Duration
+	entries map[string]entry
+}
+
+// NewStore creates a Store whose entries expire ttl after being Set.
+func NewStore(ttl time.Duration) *Store {
+	return NewStoreWithClock(ttl, clockwork.NewRealClock())
+}
+
+// NewStoreWithClock is like NewStore but takes an explicit clock, so tests
+// can control expiry deterministically.
+func NewStoreWithClock(ttl time.Duration, clock clockwork.Clock) *Store {
+	return &Store{
+		clock:   clock,
+		ttl:     ttl,
+		entries: make(map[string]entry),
+	}
+}
+
+// Get returns the value cached for key, if any, and whether it was found and
+// hasn't yet expired.
+func (s *Store) Get(key string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok || !s.clock.Now().Before(e.expiresAt) {
+		return "", false
+	}
+	return e.value, true
+}
+
+// Set caches value under key for the store's TTL. It also sweeps any other
+// expired entries while it holds the lock, so the map doesn't grow
+// unbounded over the life of a long-running server.
+func (s *Store) Set(key, value string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.entries[key] = entry{value: value, expiresAt: now.Add(s.ttl)}
+	for k, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+}
+
+// GetOrSet returns the value already cached for key, if any and unexpired.
+// Otherwise it calls create to produce one, caches it, and returns it. The
+// whole check-then-create-then-cache sequence runs under the store's lock,
+// so two concurrent callers racing on the same key can never both observe a
+// miss and both run create - exactly one wins and the other gets its
+// result back with loaded set to true. create is not called at all when an
+// unexpired entry already exists.
+func (s *Store) GetOrSet(key string, create func() (string, error)) (value string, loaded bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	if e, ok := s.entries[key]; ok && now.Before(e.expiresAt) {
+		return e.value, true, nil
+	}
+
+	value, err = create()
+	if err != nil {
+		return "", false, err
+	}
+
+	s.entries[key] = entry{value: value, expiresAt: now.Add(s.ttl)}
+	for k, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, k)
+		}
+	}
+	return value, false, nil
+}
@@ -0,0 +1,137 @@
+package idempotency
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_GetMissingKey(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	_, ok := store.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestStore_SetAndGet(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	store.Set("key-1", "result-1")
+
+	value, ok := store.Get("key-1")
+	require.True(t, ok)
+	assert.Equal(t, "result-1", value)
+}
+
+func TestStore_DifferentKeysDontCollide(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	store.Set("key-1", "result-1")
+
+	_, ok := store.Get("key-2")
+	assert.False(t, ok)
+}
+
+func TestStore_EntryExpiresAfterTTL(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(time.Minute, clock)
+
+	store.Set("key-1", "result-1")
+
+	clock.Advance(59 * time.Second)
+	_, ok := store.Get("key-1")
+	assert.True(t, ok)
+
+	clock.Advance(2 * time.Second)
+	_, ok = store.Get("key-1")
+	assert.False(t, ok)
+}
+
+func TestStore_GetOrSet_MissingKeyCallsCreate(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	value, loaded, err := store.GetOrSet("key-1", func() (string, error) {
+		return "result-1", nil
+	})
+
+	require.NoError(t, err)
+	assert.False(t, loaded)
+	assert.Equal(t, "result-1", value)
+
+	cached, ok := store.Get("key-1")
+	require.True(t, ok)
+	assert.Equal(t, "result-1", cached)
+}
+
+func TestStore_GetOrSet_ExistingKeySkipsCreate(t *testing.T) {
+	store := NewStore(time.Minute)
+	store.Set("key-1", "result-1")
+
+	value, loaded, err := store.GetOrSet("key-1", func() (string, error) {
+		t.Fatal("create should not run for an already-cached key")
+		return "", nil
+	})
+
+	require.NoError(t, err)
+	assert.True(t, loaded)
+	assert.Equal(t, "result-1", value)
+}
+
+func TestStore_GetOrSet_CreateErrorIsNotCached(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	_, loaded, err := store.GetOrSet("key-1", func() (string, error) {
+		return "", errors.New("boom")
+	})
+	require.Error(t, err)
+	assert.False(t, loaded)
+
+	_, ok := store.Get("key-1")
+	assert.False(t, ok, "a failed create must not leave a cached entry behind")
+}
+
+func TestStore_GetOrSet_ConcurrentCallersOnlyRunCreateOnce(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	var calls atomic.Int32
+	var wg sync.WaitGroup
+	results := make([]string, 20)
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, _, err := store.GetOrSet("key-1", func() (string, error) {
+				calls.Add(1)
+				return "result-1", nil
+			})
+			require.NoError(t, err)
+			results[i] = value
+		}(i)
+	}
+	wg.Wait()
+
+	assert.EqualValues(t, 1, calls.Load(), "create must run exactly once across all concurrent callers")
+	for _, v := range results {
+		assert.Equal(t, "result-1", v)
+	}
+}
+
+func TestStore_SetSweepsOtherExpiredEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(time.Minute, clock)
+
+	store.Set("key-1", "result-1")
+	clock.Advance(2 * time.Minute)
+
+	store.Set("key-2", "result-2")
+
+	assert.Len(t, store.entries, 1)
+	_, ok := store.entries["key-1"]
+	assert.False(t, ok)
+}
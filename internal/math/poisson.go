@@ -3,30 +3,173 @@ package math
 import (
 	"math"
 	"math/rand"
+	"sync"
 )
 
-// GetPoissonDelay generates a random delay around a target mean (lambda) in milliseconds.
-// Uses exponential distribution which models the time between events in a Poisson process.
-// Time = -ln(U) * mean, where U is uniform random [0,1)
-func GetPoissonDelay(targetMean float64) int {
-	// Ensure we don't get -Inf from log(0)
-	u := rand.Float64()
-	for u == 0 {
-		u = rand.Float64()
+// DefaultMinClampFactor and DefaultMaxClampFactor are the min/max clamp
+// factors a Generator uses until SetClampFactors overrides them - a draw is
+// never allowed below DefaultMinClampFactor*mean or above
+// DefaultMaxClampFactor*mean.
+const (
+	DefaultMinClampFactor = 0.1
+	DefaultMaxClampFactor = 10
+)
+
+// Generator produces refresh delays from its own *rand.Rand, rather than
+// the math/rand package-level source. A caller that wants a fixed seed for
+// deterministic tests, or its own source so concurrent delay generation
+// doesn't contend with unrelated callers, should hold a Generator instead
+// of using GetPoissonDelay/GetPoissonCount.
+type Generator struct {
+	rng       *rand.Rand
+	minFactor float64
+	maxFactor float64
+}
+
+// NewGenerator returns a Generator seeded with seed, clamping its draws to
+// DefaultMinClampFactor/DefaultMaxClampFactor of the mean until
+// SetClampFactors overrides them. Two Generators built with the same seed
+// produce identical Delay/Count sequences; a Generator is not safe for
+// concurrent use by multiple goroutines, same as the underlying *rand.Rand.
+func NewGenerator(seed int64) *Generator {
+	return &Generator{
+		rng:       rand.New(rand.NewSource(seed)),
+		minFactor: DefaultMinClampFactor,
+		maxFactor: DefaultMaxClampFactor,
+	}
+}
+
+// SetClampFactors changes the bounds Delay/Count clamp their draw to,
+// relative to the mean: a draw never lands below min*mean or above
+// max*mean. Both must be positive and min must be less than max; an
+// invalid pair is ignored and the Generator keeps using its current
+// factors, so a bad config value degrades to the previous behavior instead
+// of producing a nonsensical clamp.
+func (g *Generator) SetClampFactors(min, max float64) {
+	if min <= 0 || max <= 0 || min >= max {
+		return
 	}
+	g.minFactor = min
+	g.maxFactor = max
+}
 
+// Delay generates a random delay around a target mean (lambda) in
+// milliseconds, using an exponential distribution - the distribution of
+// inter-arrival times in a Poisson process. Time = -ln(U) * mean, where U
+// is uniform random [0,1). This is continuous: any non-negative real value
+// is possible, and successive delays are independent of one another.
+// Clamped to bounds relative to the mean (see SetClampFactors) so an
+// unlucky draw can't produce an unreasonably short or long delay.
+func (g *Generator) Delay(targetMean float64) int {
+	u := g.rng.Float64()
+	for u == 0 {
+		// Ensure we don't get -Inf from log(0)
+		u = g.rng.Float64()
+	}
 	delay := int(-math.Log(u) * targetMean)
+	return g.clamp(delay, targetMean)
+}
 
-	// Clamp to bounds relative to the mean (0.1x to 10x)
-	minDelay := int(0.1 * targetMean)
-	maxDelay := int(10 * targetMean)
+// knuthLambdaCutoff is where Count switches from Knuth's algorithm to a
+// normal approximation. Knuth's algorithm computes exp(-lambda), which
+// underflows to 0 for a large lambda (making every draw come out 0), long
+// before lambda reaches a magnitude generateDelayQueue actually uses
+// (refresh intervals in the thousands of milliseconds).
+const knuthLambdaCutoff = 30
 
-	if delay < minDelay {
-		delay = minDelay
+// Count draws from a discrete Poisson distribution with mean lambda, then
+// clamps it the same way Delay clamps its exponential draw (see
+// SetClampFactors). Unlike Delay - which models the gap between two events
+// - Count models the number of events themselves (here, treated directly
+// as a millisecond delay), so it only ever returns values near lambda; it
+// doesn't have the exponential's long right tail of occasional very large
+// delays.
+func (g *Generator) Count(lambda float64) int {
+	if lambda <= 0 {
+		return g.clamp(0, lambda)
+	}
+	if lambda < knuthLambdaCutoff {
+		return g.clamp(g.knuthPoisson(lambda), lambda)
 	}
-	if delay > maxDelay {
-		delay = maxDelay
+	return g.clamp(g.normalApproxPoisson(lambda), lambda)
+}
+
+// knuthPoisson draws an exact Poisson sample via Knuth's algorithm: keep
+// multiplying by uniform random values until the running product drops
+// below exp(-lambda), and count how many multiplications it took.
+func (g *Generator) knuthPoisson(lambda float64) int {
+	l := math.Exp(-lambda)
+	k := 0
+	p := 1.0
+	for {
+		k++
+		p *= g.rng.Float64()
+		if p <= l {
+			break
+		}
 	}
+	return k - 1
+}
+
+// normalApproxPoisson draws from Normal(lambda, sqrt(lambda)) via the
+// Box-Muller transform and rounds to the nearest integer - the standard
+// large-lambda approximation for a Poisson distribution, whose shape
+// converges to a normal one as lambda grows.
+func (g *Generator) normalApproxPoisson(lambda float64) int {
+	u1 := g.rng.Float64()
+	for u1 == 0 {
+		u1 = g.rng.Float64()
+	}
+	u2 := g.rng.Float64()
+	z := math.Sqrt(-2*math.Log(u1)) * math.Cos(2*math.Pi*u2)
+
+	count := int(math.Round(lambda + z*math.Sqrt(lambda)))
+	if count < 0 {
+		count = 0
+	}
+	return count
+}
+
+// clamp bounds value to g.minFactor-g.maxFactor of mean, the shared bound
+// Delay and Count both enforce so a caller can switch between the two
+// distributions without its delay queue's worst case changing.
+func (g *Generator) clamp(value int, mean float64) int {
+	minDelay := int(g.minFactor * mean)
+	maxDelay := int(g.maxFactor * mean)
+	if value < minDelay {
+		value = minDelay
+	}
+	if value > maxDelay {
+		value = maxDelay
+	}
+	return value
+}
+
+// sharedGenerator backs GetPoissonDelay/GetPoissonCount. A *rand.Rand isn't
+// safe for concurrent use on its own (unlike the math/rand package-level
+// functions, which lock internally), so sharedMu serializes access to it.
+var (
+	sharedMu        sync.Mutex
+	sharedGenerator = NewGenerator(1)
+)
+
+// GetPoissonDelay is a package-level convenience around a shared,
+// mutex-guarded Generator, for callers that don't need their own source or
+// a fixed seed. A server (or other long-lived caller that wants
+// deterministic tests or an isolated source) should hold its own
+// *Generator via NewGenerator instead. See Generator.Delay for the
+// distribution this samples from.
+func GetPoissonDelay(targetMean float64) int {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	return sharedGenerator.Delay(targetMean)
+}
 
-	return delay
+// GetPoissonCount is GetPoissonDelay's counterpart for a discrete Poisson
+// draw - see Generator.Count for the distribution this samples from and
+// how it differs from GetPoissonDelay's exponential one.
+func GetPoissonCount(lambda float64) int {
+	sharedMu.Lock()
+	defer sharedMu.Unlock()
+	return sharedGenerator.Count(lambda)
 }
@@ -2,65 +2,63 @@ package math
 
 import (
 	"math"
-	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
-func TestGetPoissonDelay_BoundsEnforced(t *testing.T) {
-	// Seed for reproducibility in tests
-	rand.Seed(42)
+func TestGenerator_BoundsEnforced(t *testing.T) {
+	gen := NewGenerator(42)
 
 	targetMean := 1000.0
-	minBound := int(0.1 * targetMean) // 100
-	maxBound := int(10 * targetMean)  // 10000
+	minBound := int(DefaultMinClampFactor * targetMean) // 100
+	maxBound := int(DefaultMaxClampFactor * targetMean) // 10000
 
 	// Run many iterations to check bounds
 	for i := 0; i < 10000; i++ {
-		delay := GetPoissonDelay(targetMean)
+		delay := gen.Delay(targetMean)
 		assert.GreaterOrEqual(t, delay, minBound, "delay should be >= min bound (0.1x mean)")
 		assert.LessOrEqual(t, delay, maxBound, "delay should be <= max bound (10x mean)")
 	}
 }
 
-func TestGetPoissonDelay_SmallMean(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_SmallMean(t *testing.T) {
+	gen := NewGenerator(42)
 
 	targetMean := 10.0
-	minBound := int(0.1 * targetMean) // 1
-	maxBound := int(10 * targetMean)  // 100
+	minBound := int(DefaultMinClampFactor * targetMean) // 1
+	maxBound := int(DefaultMaxClampFactor * targetMean) // 100
 
 	for i := 0; i < 1000; i++ {
-		delay := GetPoissonDelay(targetMean)
+		delay := gen.Delay(targetMean)
 		assert.GreaterOrEqual(t, delay, minBound)
 		assert.LessOrEqual(t, delay, maxBound)
 	}
 }
 
-func TestGetPoissonDelay_LargeMean(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_LargeMean(t *testing.T) {
+	gen := NewGenerator(42)
 
 	targetMean := 100000.0
-	minBound := int(0.1 * targetMean) // 10000
-	maxBound := int(10 * targetMean)  // 1000000
+	minBound := int(DefaultMinClampFactor * targetMean) // 10000
+	maxBound := int(DefaultMaxClampFactor * targetMean) // 1000000
 
 	for i := 0; i < 1000; i++ {
-		delay := GetPoissonDelay(targetMean)
+		delay := gen.Delay(targetMean)
 		assert.GreaterOrEqual(t, delay, minBound)
 		assert.LessOrEqual(t, delay, maxBound)
 	}
 }
 
-func TestGetPoissonDelay_DistributionReasonable(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_DistributionReasonable(t *testing.T) {
+	gen := NewGenerator(42)
 
 	targetMean := 1000.0
 	iterations := 10000
 
 	var sum float64
 	for i := 0; i < iterations; i++ {
-		delay := GetPoissonDelay(targetMean)
+		delay := gen.Delay(targetMean)
 		sum += float64(delay)
 	}
 
@@ -73,8 +71,8 @@ func TestGetPoissonDelay_DistributionReasonable(t *testing.T) {
 		"mean of delays should be approximately the target mean")
 }
 
-func TestGetPoissonDelay_Variance(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_Variance(t *testing.T) {
+	gen := NewGenerator(42)
 
 	targetMean := 1000.0
 	iterations := 10000
@@ -82,7 +80,7 @@ func TestGetPoissonDelay_Variance(t *testing.T) {
 	delays := make([]float64, iterations)
 	var sum float64
 	for i := 0; i < iterations; i++ {
-		delay := float64(GetPoissonDelay(targetMean))
+		delay := float64(gen.Delay(targetMean))
 		delays[i] = delay
 		sum += delay
 	}
@@ -103,42 +101,58 @@ func TestGetPoissonDelay_Variance(t *testing.T) {
 	assert.Greater(t, stdDev, targetMean*0.1, "variance should be meaningful")
 }
 
-func TestGetPoissonDelay_VerySmallMean(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_VerySmallMean(t *testing.T) {
+	gen := NewGenerator(42)
 
 	// Edge case: very small mean (like 1 ms)
 	targetMean := 1.0
-	minBound := int(0.1 * targetMean) // 0
-	maxBound := int(10 * targetMean)  // 10
+	minBound := int(DefaultMinClampFactor * targetMean) // 0
+	maxBound := int(DefaultMaxClampFactor * targetMean) // 10
 
 	for i := 0; i < 1000; i++ {
-		delay := GetPoissonDelay(targetMean)
+		delay := gen.Delay(targetMean)
 		assert.GreaterOrEqual(t, delay, minBound)
 		assert.LessOrEqual(t, delay, maxBound)
 	}
 }
 
-func TestGetPoissonDelay_ReturnsInteger(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_ReturnsInteger(t *testing.T) {
+	gen := NewGenerator(42)
 
-	delay := GetPoissonDelay(1000.0)
+	delay := gen.Delay(1000.0)
 	// Type assertion - if it compiles, it's an int
 	var _ int = delay
 	assert.IsType(t, 0, delay)
 }
 
-func TestGetPoissonDelay_DifferentSeeds(t *testing.T) {
+func TestNewGenerator_SameSeedProducesSameSequence(t *testing.T) {
+	seq1 := make([]int, 10)
+	gen1 := NewGenerator(7)
+	for i := range seq1 {
+		seq1[i] = gen1.Delay(1000.0)
+	}
+
+	seq2 := make([]int, 10)
+	gen2 := NewGenerator(7)
+	for i := range seq2 {
+		seq2[i] = gen2.Delay(1000.0)
+	}
+
+	assert.Equal(t, seq1, seq2, "the same seed should produce the same sequence")
+}
+
+func TestNewGenerator_DifferentSeeds(t *testing.T) {
 	// Test that different seeds produce different sequences
-	rand.Seed(1)
+	gen1 := NewGenerator(1)
 	seq1 := make([]int, 10)
 	for i := range seq1 {
-		seq1[i] = GetPoissonDelay(1000.0)
+		seq1[i] = gen1.Delay(1000.0)
 	}
 
-	rand.Seed(2)
+	gen2 := NewGenerator(2)
 	seq2 := make([]int, 10)
 	for i := range seq2 {
-		seq2[i] = GetPoissonDelay(1000.0)
+		seq2[i] = gen2.Delay(1000.0)
 	}
 
 	// Sequences should differ
@@ -152,22 +166,181 @@ func TestGetPoissonDelay_DifferentSeeds(t *testing.T) {
 	assert.True(t, different, "different seeds should produce different sequences")
 }
 
-func TestGetPoissonDelay_ZeroMean(t *testing.T) {
-	rand.Seed(42)
+func TestGenerator_ZeroMean(t *testing.T) {
+	gen := NewGenerator(42)
 
 	// Edge case: zero mean
 	// min = 0, max = 0, so all results should be 0
 	targetMean := 0.0
 
 	for i := 0; i < 100; i++ {
-		delay := GetPoissonDelay(targetMean)
+		delay := gen.Delay(targetMean)
 		assert.Equal(t, 0, delay, "zero mean should produce zero delay")
 	}
 }
 
-func BenchmarkGetPoissonDelay(b *testing.B) {
-	rand.Seed(42)
+func TestGenerator_SetClampFactors_NarrowsBounds(t *testing.T) {
+	gen := NewGenerator(42)
+	gen.SetClampFactors(0.5, 2)
+
+	targetMean := 1000.0
+	minBound := int(0.5 * targetMean)
+	maxBound := int(2 * targetMean)
+
+	for i := 0; i < 10000; i++ {
+		delay := gen.Delay(targetMean)
+		assert.GreaterOrEqual(t, delay, minBound)
+		assert.LessOrEqual(t, delay, maxBound)
+
+		count := gen.Count(targetMean)
+		assert.GreaterOrEqual(t, count, minBound)
+		assert.LessOrEqual(t, count, maxBound)
+	}
+}
+
+func TestGenerator_SetClampFactors_RejectsInvalidPairs(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		min, max float64
+	}{
+		{"min not positive", 0, 2},
+		{"max not positive", 0.5, 0},
+		{"min equal to max", 1, 1},
+		{"min greater than max", 2, 1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			gen := NewGenerator(42)
+			gen.SetClampFactors(tc.min, tc.max)
+
+			// An invalid pair is ignored, so the default factors still apply.
+			targetMean := 1000.0
+			delay := gen.Delay(targetMean)
+			assert.GreaterOrEqual(t, delay, int(DefaultMinClampFactor*targetMean))
+			assert.LessOrEqual(t, delay, int(DefaultMaxClampFactor*targetMean))
+		})
+	}
+}
+
+func TestGetPoissonDelay_BoundsEnforced(t *testing.T) {
+	targetMean := 1000.0
+	minBound := int(DefaultMinClampFactor * targetMean) // 100
+	maxBound := int(DefaultMaxClampFactor * targetMean) // 10000
+
+	for i := 0; i < 10000; i++ {
+		delay := GetPoissonDelay(targetMean)
+		assert.GreaterOrEqual(t, delay, minBound, "delay should be >= min bound (0.1x mean)")
+		assert.LessOrEqual(t, delay, maxBound, "delay should be <= max bound (10x mean)")
+	}
+}
+
+func TestGetPoissonDelay_ConcurrentUseIsSafe(t *testing.T) {
+	// GetPoissonDelay shares one Generator across callers, guarded by a
+	// mutex; run it from many goroutines to make sure that holds up under
+	// the race detector.
+	done := make(chan struct{})
+	for i := 0; i < 20; i++ {
+		go func() {
+			for j := 0; j < 100; j++ {
+				GetPoissonDelay(1000.0)
+			}
+			done <- struct{}{}
+		}()
+	}
+	for i := 0; i < 20; i++ {
+		<-done
+	}
+}
+
+func TestGenerator_Count_BoundsEnforced(t *testing.T) {
+	gen := NewGenerator(42)
+
+	lambda := 1000.0
+	minBound := int(DefaultMinClampFactor * lambda)
+	maxBound := int(DefaultMaxClampFactor * lambda)
+
+	for i := 0; i < 10000; i++ {
+		count := gen.Count(lambda)
+		assert.GreaterOrEqual(t, count, minBound)
+		assert.LessOrEqual(t, count, maxBound)
+	}
+}
+
+func TestGenerator_Count_DistributionReasonable(t *testing.T) {
+	gen := NewGenerator(42)
+
+	lambda := 1000.0
+	iterations := 5000
+
+	var sum float64
+	for i := 0; i < iterations; i++ {
+		sum += float64(gen.Count(lambda))
+	}
+	actualMean := sum / float64(iterations)
+
+	// A Poisson distribution's mean is lambda; allow a modest tolerance
+	// since the discrete draw and clamping both add noise.
+	tolerance := 0.1 * lambda
+	assert.InDelta(t, lambda, actualMean, tolerance,
+		"mean of counts should be approximately lambda")
+}
+
+func TestGenerator_Count_TighterSpreadThanDelay(t *testing.T) {
+	// Count (discrete Poisson) has a much smaller variance than Delay
+	// (exponential) at the same mean - stddev sqrt(lambda) vs lambda - so
+	// it shouldn't produce the exponential's occasional very large draws.
+	gen := NewGenerator(42)
+
+	lambda := 1000.0
+	iterations := 5000
+
+	var maxCount int
+	for i := 0; i < iterations; i++ {
+		if c := gen.Count(lambda); c > maxCount {
+			maxCount = c
+		}
+	}
+
+	assert.Less(t, maxCount, int(3*lambda),
+		"Poisson counts should stay much closer to the mean than the exponential's 10x clamp allows")
+}
+
+func TestGenerator_Count_ZeroLambda(t *testing.T) {
+	gen := NewGenerator(42)
+
+	for i := 0; i < 100; i++ {
+		assert.Equal(t, 0, gen.Count(0))
+	}
+}
+
+func TestGenerator_Count_ReturnsInteger(t *testing.T) {
+	gen := NewGenerator(42)
+
+	count := gen.Count(1000.0)
+	assert.IsType(t, 0, count)
+}
+
+func TestGetPoissonCount_BoundsEnforced(t *testing.T) {
+	lambda := 1000.0
+	minBound := int(DefaultMinClampFactor * lambda)
+	maxBound := int(DefaultMaxClampFactor * lambda)
+
+	for i := 0; i < 1000; i++ {
+		count := GetPoissonCount(lambda)
+		assert.GreaterOrEqual(t, count, minBound)
+		assert.LessOrEqual(t, count, maxBound)
+	}
+}
+
+func BenchmarkGenerator_Delay(b *testing.B) {
+	gen := NewGenerator(42)
+	for i := 0; i < b.N; i++ {
+		gen.Delay(1000.0)
+	}
+}
+
+func BenchmarkGenerator_Count(b *testing.B) {
+	gen := NewGenerator(42)
 	for i := 0; i < b.N; i++ {
-		GetPoissonDelay(1000.0)
+		gen.Count(1000.0)
 	}
 }
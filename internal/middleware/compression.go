@@ -0,0 +1,71 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"strings"
+
+	"pahg-template/internal/config"
+)
+
+// bufferingResponseWriter captures the response body so CompressionMiddleware
+// can decide whether it's worth compressing once the full size is known.
+type bufferingResponseWriter struct {
+	http.ResponseWriter
+	buf        bytes.Buffer
+	statusCode int
+}
+
+func (w *bufferingResponseWriter) WriteHeader(code int) {
+	w.statusCode = code
+}
+
+func (w *bufferingResponseWriter) Write(b []byte) (int, error) {
+	return w.buf.Write(b)
+}
+
+// IsEventStreamRequest reports whether r is a Server-Sent Events request
+// (an EventSource client always sends this Accept header). Middleware that
+// buffers a response before writing it - compression, error formatting -
+// must bypass that buffering for these requests, since an SSE handler needs
+// to flush frames to the client as they're produced rather than once the
+// handler returns.
+func IsEventStreamRequest(r *http.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
+// CompressionMiddleware gzip-compresses responses at or above the configured
+// size threshold. Small HTMX fragments (e.g. a single ticker row) are left
+// uncompressed, since gzipping them costs more CPU than it saves on the wire.
+func CompressionMiddleware(cfg *config.CompressionConfig) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !cfg.Enabled || !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || IsEventStreamRequest(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			bw := &bufferingResponseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(bw, r)
+
+			body := bw.buf.Bytes()
+			if len(body) < cfg.MinSizeBytes {
+				w.WriteHeader(bw.statusCode)
+				w.Write(body)
+				return
+			}
+
+			var gzBuf bytes.Buffer
+			gz := gzip.NewWriter(&gzBuf)
+			gz.Write(body)
+			gz.Close()
+
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Header().Set("Vary", "Accept-Encoding")
+			w.Header().Del("Content-Length")
+			w.WriteHeader(bw.statusCode)
+			w.Write(gzBuf.Bytes())
+		})
+	}
+}
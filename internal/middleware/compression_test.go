@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"pahg-template/internal/config"
+)
+
+func TestCompressionMiddleware_SmallResponseNotCompressed(t *testing.T) {
+	cfg := &config.CompressionConfig{Enabled: true, MinSizeBytes: 1024}
+
+	handler := CompressionMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<tr><td>BTC</td></tr>"))
+	}))
+
+	req := httptest.NewRequest("GET", "/ticker", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, "<tr><td>BTC</td></tr>", rec.Body.String())
+}
+
+func TestCompressionMiddleware_LargeResponseCompressed(t *testing.T) {
+	cfg := &config.CompressionConfig{Enabled: true, MinSizeBytes: 1024}
+
+	large := strings.Repeat("x", 2048)
+	handler := CompressionMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	decoded, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, large, string(decoded))
+}
+
+func TestCompressionMiddleware_Disabled(t *testing.T) {
+	cfg := &config.CompressionConfig{Enabled: false, MinSizeBytes: 1024}
+
+	large := strings.Repeat("x", 2048)
+	handler := CompressionMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, rec.Body.String())
+}
+
+func TestCompressionMiddleware_NoAcceptEncoding(t *testing.T) {
+	cfg := &config.CompressionConfig{Enabled: true, MinSizeBytes: 1024}
+
+	large := strings.Repeat("x", 2048)
+	handler := CompressionMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("Content-Encoding"))
+	assert.Equal(t, large, rec.Body.String())
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import "net/http"
+
+// corsAllowedMethods and corsAllowedHeaders are sent on every CORS response,
+// covering the read-only JSON API endpoints this middleware guards plus the
+// headers HTMX/fetch callers might attach to a cross-origin request.
+const (
+	corsAllowedMethods = "GET, OPTIONS"
+	corsAllowedHeaders = "Content-Type, Accept"
+)
+
+// CORSMiddleware sets cross-origin headers for requests to isAPIPath paths,
+// letting a separate-origin client (e.g. an admin SPA) consume this app's
+// JSON endpoints. It deliberately never applies to HTML pages, so it can't
+// be used to read a page rendered under the session cookie from another
+// origin. allowedOrigins lists origins to echo back via
+// Access-Control-Allow-Origin; a request from any other origin, or with no
+// Origin header, gets no CORS headers and is handled normally. Credentials
+// are never allowed (no Access-Control-Allow-Credentials), keeping this
+// strictly a read-only JSON surface separate from the cookie-based session
+// auth used by the HTML pages.
+func CORSMiddleware(allowedOrigins []string, isAPIPath func(path string) bool) func(http.Handler) http.Handler {
+	allowed := make(map[string]bool, len(allowedOrigins))
+	for _, origin := range allowedOrigins {
+		allowed[origin] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if len(allowed) == 0 || !isAPIPath(r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			origin := r.Header.Get("Origin")
+			if origin != "" && allowed[origin] {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+				w.Header().Set("Vary", "Origin")
+				w.Header().Set("Access-Control-Allow-Methods", corsAllowedMethods)
+				w.Header().Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+			}
+
+			if r.Method == http.MethodOptions {
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
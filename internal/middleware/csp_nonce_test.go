@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCSPNonceMiddleware_SetsContext(t *testing.T) {
+	var contextNonce string
+	handler := CSPNonceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contextNonce = GetCSPNonce(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	require.NotEmpty(t, contextNonce)
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"), "CSP header is SecurityHeadersMiddleware's responsibility")
+}
+
+func TestCSPNonceMiddleware_UniqueNoncesPerRequest(t *testing.T) {
+	var lastNonce string
+	handler := CSPNonceMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastNonce = GetCSPNonce(r.Context())
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	nonces := make(map[string]bool)
+	for i := 0; i < 20; i++ {
+		req := httptest.NewRequest("GET", "/test", nil)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+
+		assert.False(t, nonces[lastNonce], "nonce should be unique per request")
+		nonces[lastNonce] = true
+	}
+}
+
+func TestGetCSPNonce_WithoutValue(t *testing.T) {
+	assert.Empty(t, GetCSPNonce(context.Background()))
+}
+
+func TestGetCSPNonce_WrongType(t *testing.T) {
+	ctx := context.WithValue(context.Background(), cspNonceKey, 12345)
+	assert.Empty(t, GetCSPNonce(ctx))
+}
@@ -0,0 +1,138 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// debugBodyMaxBytes caps how much of a request/response body
+// DebugBodyLoggingMiddleware logs, so a large payload doesn't flood the log.
+const debugBodyMaxBytes = 2048
+
+// debugRedactedFields lists JSON field names whose values are replaced with
+// "[REDACTED]" before a body is logged, so a captured /auth request never
+// leaks a submitted password.
+var debugRedactedFields = map[string]bool{
+	"password": true,
+}
+
+// redactBody returns body with any debugRedactedFields values replaced and
+// truncated to debugBodyMaxBytes. A body that isn't a JSON object is
+// returned truncated but otherwise unchanged, since redaction only
+// understands the JSON credential fields this middleware exists to protect.
+func redactBody(body []byte) string {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err == nil {
+		redacted := false
+		for key := range fields {
+			if debugRedactedFields[strings.ToLower(key)] {
+				fields[key] = "[REDACTED]"
+				redacted = true
+			}
+		}
+		if redacted {
+			if out, err := json.Marshal(fields); err == nil {
+				body = out
+			}
+		}
+	}
+
+	if len(body) > debugBodyMaxBytes {
+		return string(body[:debugBodyMaxBytes]) + "...[truncated]"
+	}
+	return string(body)
+}
+
+// isDebugLoggedPath reports whether path starts with any of debugPaths.
+func isDebugLoggedPath(path string, debugPaths []string) bool {
+	for _, prefix := range debugPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// bodyCapturingWriter wraps http.ResponseWriter to also buffer (up to
+// debugBodyMaxBytes) everything written to the response, so
+// DebugBodyLoggingMiddleware can log it after the handler returns.
+type bodyCapturingWriter struct {
+	http.ResponseWriter
+	statusCode int
+	body       bytes.Buffer
+}
+
+func newBodyCapturingWriter(w http.ResponseWriter) *bodyCapturingWriter {
+	return &bodyCapturingWriter{ResponseWriter: w, statusCode: http.StatusOK}
+}
+
+func (w *bodyCapturingWriter) WriteHeader(code int) {
+	w.statusCode = code
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *bodyCapturingWriter) Write(b []byte) (int, error) {
+	if remaining := debugBodyMaxBytes - w.body.Len(); remaining > 0 {
+		if remaining > len(b) {
+			remaining = len(b)
+		}
+		w.body.Write(b[:remaining])
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Flush delegates to the wrapped ResponseWriter's Flush when it supports
+// one, so a streaming handler further down the chain still works.
+func (w *bodyCapturingWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// DebugBodyLoggingMiddleware logs truncated, credential-redacted request and
+// response bodies for paths in debugPaths, but only when logLevel is
+// "debug". This is a targeted aid for debugging something like the /auth
+// flow or a single misbehaving endpoint - not a general request/response
+// logger, since logging arbitrary bodies risks both log volume and leaking
+// secrets.
+func DebugBodyLoggingMiddleware(logLevel string, debugPaths []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !strings.EqualFold(logLevel, "debug") || len(debugPaths) == 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !isDebugLoggedPath(r.URL.Path, debugPaths) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			reqID := GetRequestID(r.Context())
+
+			var reqBody []byte
+			if r.Body != nil {
+				reqBody, _ = io.ReadAll(r.Body)
+				r.Body = io.NopCloser(bytes.NewReader(reqBody))
+			}
+			slog.Debug("debug_request_body",
+				"request_id", reqID,
+				"path", r.URL.Path,
+				"body", redactBody(reqBody),
+			)
+
+			wrapped := newBodyCapturingWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			slog.Debug("debug_response_body",
+				"request_id", reqID,
+				"path", r.URL.Path,
+				"status", wrapped.statusCode,
+				"body", redactBody(wrapped.body.Bytes()),
+			)
+		})
+	}
+}
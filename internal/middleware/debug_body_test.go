@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDebugBodyLoggingMiddleware_RedactsPasswordField(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(logger)
+
+	var bodySeenByHandler []byte
+	handler := DebugBodyLoggingMiddleware("debug", []string{"/auth"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bodySeenByHandler, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"username":"alice","password":"hunter2"}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, `{"username":"alice","password":"hunter2"}`, string(bodySeenByHandler), "downstream handler should still see the original body")
+
+	output := buf.String()
+	assert.Contains(t, output, "debug_request_body")
+	assert.Contains(t, output, "[REDACTED]")
+	assert.NotContains(t, output, "hunter2")
+}
+
+func TestDebugBodyLoggingMiddleware_NonAllowlistedPathNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(logger)
+
+	handler := DebugBodyLoggingMiddleware("debug", []string{"/auth"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"ok":true}`))
+	}))
+
+	req := httptest.NewRequest("GET", "/ticker/changes", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, buf.String(), "a path outside the allowlist should never be logged")
+}
+
+func TestDebugBodyLoggingMiddleware_NotDebugLevelNotLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(logger)
+
+	handler := DebugBodyLoggingMiddleware("info", []string{"/auth"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/auth/login", strings.NewReader(`{"password":"hunter2"}`))
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, buf.String(), "bodies should only be logged when logging.level is debug")
+}
+
+func TestDebugBodyLoggingMiddleware_LogsResponseBody(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	slog.SetDefault(logger)
+
+	handler := DebugBodyLoggingMiddleware("debug", []string{"/auth"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"status":"ok"}`))
+	}))
+
+	req := httptest.NewRequest("POST", "/auth/login", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, `{"status":"ok"}`, rec.Body.String(), "response should still reach the real client")
+
+	output := buf.String()
+	assert.Contains(t, output, "debug_response_body")
+	assert.Contains(t, output, `status`)
+	assert.Contains(t, output, `ok`)
+}
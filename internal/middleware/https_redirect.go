@@ -0,0 +1,53 @@
+package middleware
+
+import (
+	"net"
+	"net/http"
+)
+
+// httpsRedirectExemptPaths lists paths that must keep responding over plain
+// HTTP even when force_https is enabled, so health probes (which often
+// don't speak TLS) don't start failing.
+var httpsRedirectExemptPaths = map[string]bool{
+	"/health":     true,
+	"/api/health": true,
+}
+
+// HTTPSRedirectMiddleware 301-redirects plain-HTTP requests to HTTPS when
+// enabled. It considers a request secure if r.TLS is set (direct TLS
+// termination) or X-Forwarded-Proto is "https" from a trusted proxy in
+// front of the app - see IsSecureRequest and config.SecurityConfig.TrustedProxies.
+func HTTPSRedirectMiddleware(enabled bool, trustedProxies []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !enabled || IsSecureRequest(r, trustedProxies) || httpsRedirectExemptPaths[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			target := "https://" + r.Host + r.URL.RequestURI()
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+		})
+	}
+}
+
+// IsSecureRequest reports whether the request arrived over TLS, either
+// terminated directly by this process or by a trusted proxy in front of it.
+// X-Forwarded-Proto is only honored when the immediate peer (r.RemoteAddr)
+// is one of trustedProxies - the same scoping getClientIP applies to
+// X-Forwarded-For/X-Real-IP - so a direct or on-path client can't spoof
+// "https" and make the app treat a plaintext connection as secure.
+func IsSecureRequest(r *http.Request, trustedProxies []string) bool {
+	if r.TLS != nil {
+		return true
+	}
+	if r.Header.Get("X-Forwarded-Proto") != "https" {
+		return false
+	}
+
+	remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remoteHost = r.RemoteAddr
+	}
+	return isTrustedProxy(remoteHost, parseCIDRs(trustedProxies))
+}
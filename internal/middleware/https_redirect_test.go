@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPSRedirectMiddleware_Disabled(t *testing.T) {
+	called := false
+	handler := HTTPSRedirectMiddleware(false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.com/ticker", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHTTPSRedirectMiddleware_RedirectsPlainHTTP(t *testing.T) {
+	called := false
+	handler := HTTPSRedirectMiddleware(true, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.com/ticker?x=1", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+	assert.Equal(t, "https://example.com/ticker?x=1", rec.Header().Get("Location"))
+}
+
+func TestHTTPSRedirectMiddleware_AllowsForwardedProtoHTTPSFromTrustedProxy(t *testing.T) {
+	called := false
+	handler := HTTPSRedirectMiddleware(true, []string{"192.0.2.0/24"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.com/ticker", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHTTPSRedirectMiddleware_IgnoresForwardedProtoHTTPSFromUntrustedClient(t *testing.T) {
+	called := false
+	handler := HTTPSRedirectMiddleware(true, []string{"192.0.2.0/24"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("GET", "http://example.com/ticker", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.RemoteAddr = "203.0.113.5:1234"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.False(t, called, "a spoofed header from a client outside trustedProxies must not be honored")
+	assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+}
+
+func TestHTTPSRedirectMiddleware_AllowsDirectTLS(t *testing.T) {
+	called := false
+	handler := HTTPSRedirectMiddleware(true, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "https://example.com/ticker", nil)
+	req.TLS = &tls.ConnectionState{}
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestHTTPSRedirectMiddleware_ExemptsHealthProbes(t *testing.T) {
+	for _, path := range []string{"/health", "/api/health"} {
+		called := false
+		handler := HTTPSRedirectMiddleware(true, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			w.WriteHeader(http.StatusOK)
+		}))
+
+		req := httptest.NewRequest("GET", "http://example.com"+path, nil)
+		rec := httptest.NewRecorder()
+
+		handler.ServeHTTP(rec, req)
+
+		assert.True(t, called, "expected %s to be exempt", path)
+		assert.Equal(t, http.StatusOK, rec.Code)
+	}
+}
@@ -2,18 +2,62 @@ package middleware
 
 import (
 	"log/slog"
+	"math/rand"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 )
 
-// responseWriter wraps http.ResponseWriter to capture the status code
+// defaultSlowRequestThreshold is used when logging.slow_request_ms is unset.
+const defaultSlowRequestThreshold = 1000 * time.Millisecond
+
+// defaultSampleRate is used when logging.sample_rate is unset, preserving
+// the historical behavior of logging every request.
+const defaultSampleRate = 1.0
+
+// sensitiveQueryParams lists query parameter names whose values are
+// redacted before request_completed logs the query string, so a stray
+// ?token=... or ?password=... never ends up in log storage.
+var sensitiveQueryParams = map[string]bool{
+	"password": true,
+	"token":    true,
+	"secret":   true,
+	"hash":     true,
+}
+
+// redactedQuery returns rawQuery with any sensitiveQueryParams values
+// replaced by "[REDACTED]". An unparseable query string is returned
+// unchanged rather than dropped, since it's still useful for debugging.
+func redactedQuery(rawQuery string) string {
+	if rawQuery == "" {
+		return ""
+	}
+
+	values, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return rawQuery
+	}
+
+	for key := range values {
+		if sensitiveQueryParams[strings.ToLower(key)] {
+			values[key] = []string{"[REDACTED]"}
+		}
+	}
+
+	return values.Encode()
+}
+
+// responseWriter wraps http.ResponseWriter to capture the status code and
+// the number of bytes written to the response body.
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int
 }
 
 func newResponseWriter(w http.ResponseWriter) *responseWriter {
-	return &responseWriter{w, http.StatusOK}
+	return &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -21,43 +65,110 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
-// LoggingMiddleware logs every HTTP request with structured logging
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += n
+	return n, err
+}
+
+// Flush delegates to the wrapped ResponseWriter's Flush when it supports
+// one, so a streaming handler (e.g. Server-Sent Events) further down the
+// chain can still push partial responses through this wrapper.
+func (rw *responseWriter) Flush() {
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
 
-		// Get client IP
-		ip := r.RemoteAddr
-		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
-			ip = forwarded
+// isSampledPath reports whether path starts with any of the given prefixes,
+// marking it eligible for sampleRate to reduce its log volume.
+func isSampledPath(path string, sampledPaths []string) bool {
+	for _, prefix := range sampledPaths {
+		if strings.HasPrefix(path, prefix) {
+			return true
 		}
+	}
+	return false
+}
+
+// LoggingMiddleware logs every HTTP request with structured logging. Requests
+// exceeding slowRequestMs additionally get a slow_request warning; a
+// slowRequestMs <= 0 falls back to defaultSlowRequestThreshold.
+//
+// Successful (status < 400) requests whose path matches sampledPaths are
+// only logged a sampleRate fraction of the time, to keep noisy health
+// checks and static assets from drowning out the rest of the log; a
+// sampleRate <= 0 falls back to defaultSampleRate (log everything). Errors
+// are always logged regardless of sampling.
+//
+// trustedProxies and trustForwardedChain scope which peers' forwarded
+// headers the logged "ip" field will honor and how a forwarded chain is
+// read - see config.SecurityConfig.TrustedProxies and TrustForwardedChain.
+func LoggingMiddleware(slowRequestMs int, sampleRate float64, sampledPaths []string, trustedProxies []string, trustForwardedChain bool) func(http.Handler) http.Handler {
+	threshold := defaultSlowRequestThreshold
+	if slowRequestMs > 0 {
+		threshold = time.Duration(slowRequestMs) * time.Millisecond
+	}
+	if sampleRate <= 0 {
+		sampleRate = defaultSampleRate
+	}
+	trusted := parseCIDRs(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			ip := getClientIP(r, trusted, trustForwardedChain)
+
+			// Get request ID from context
+			reqID := GetRequestID(r.Context())
+
+			// A denylisted path is dropped this draw unless the eventual
+			// status is an error, in which case it's always logged.
+			droppedByDefault := isSampledPath(r.URL.Path, sampledPaths) && rand.Float64() >= sampleRate
+
+			// Wrap response writer to capture status
+			wrapped := newResponseWriter(w)
+
+			// Process request
+			next.ServeHTTP(wrapped, r)
+
+			duration := time.Since(start)
+			shouldLog := !droppedByDefault || wrapped.statusCode >= http.StatusBadRequest
+			if !shouldLog {
+				return
+			}
+
+			// Log request start
+			slog.Info("request_started",
+				"request_id", reqID,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"ip", ip,
+				"user_agent", r.UserAgent(),
+			)
+
+			// Log request completion
+			slog.Info("request_completed",
+				"request_id", reqID,
+				"method", r.Method,
+				"path", r.URL.Path,
+				"query", redactedQuery(r.URL.RawQuery),
+				"status", wrapped.statusCode,
+				"bytes_written", wrapped.bytesWritten,
+				"duration_ms", float64(duration.Microseconds())/1000.0,
+			)
 
-		// Get request ID from context
-		reqID := GetRequestID(r.Context())
-
-		// Log request start
-		slog.Info("request_started",
-			"request_id", reqID,
-			"method", r.Method,
-			"path", r.URL.Path,
-			"ip", ip,
-			"user_agent", r.UserAgent(),
-		)
-
-		// Wrap response writer to capture status
-		wrapped := newResponseWriter(w)
-
-		// Process request
-		next.ServeHTTP(wrapped, r)
-
-		// Log request completion
-		duration := time.Since(start)
-		slog.Info("request_completed",
-			"request_id", reqID,
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", wrapped.statusCode,
-			"duration_ms", float64(duration.Microseconds())/1000.0,
-		)
-	})
+			if duration > threshold {
+				slog.Warn("slow_request",
+					"request_id", reqID,
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", wrapped.statusCode,
+					"duration_ms", float64(duration.Microseconds())/1000.0,
+					"threshold_ms", threshold.Milliseconds(),
+				)
+			}
+		})
+	}
 }
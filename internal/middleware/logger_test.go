@@ -8,6 +8,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
@@ -17,7 +18,7 @@ func TestLoggingMiddleware_LogsRequestStarted(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -37,7 +38,7 @@ func TestLoggingMiddleware_LogsRequestCompleted(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -56,7 +57,7 @@ func TestLoggingMiddleware_CapturesStatusCode(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusNotFound)
 	}))
 
@@ -74,7 +75,7 @@ func TestLoggingMiddleware_IncludesRequestID(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -95,7 +96,7 @@ func TestLoggingMiddleware_IncludesIP(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -106,15 +107,15 @@ func TestLoggingMiddleware_IncludesIP(t *testing.T) {
 	handler.ServeHTTP(rec, req)
 
 	output := buf.String()
-	assert.Contains(t, output, "192.168.1.100:12345")
+	assert.Contains(t, output, "192.168.1.100")
 }
 
-func TestLoggingMiddleware_UsesXForwardedFor(t *testing.T) {
+func TestLoggingMiddleware_UsesXForwardedForFromTrustedProxy(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, []string{"192.168.1.0/24"}, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -129,12 +130,54 @@ func TestLoggingMiddleware_UsesXForwardedFor(t *testing.T) {
 	assert.Contains(t, output, "10.0.0.50")
 }
 
+func TestLoggingMiddleware_TrustForwardedChainLogsRightmostUntrustedHop(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, nil, []string{"192.168.1.0/24"}, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.50, 203.0.113.7, 192.168.1.100")
+	req.RemoteAddr = "192.168.1.100:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "203.0.113.7")
+	assert.NotContains(t, output, "10.0.0.50")
+}
+
+func TestLoggingMiddleware_IgnoresXForwardedForFromUntrustedProxy(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Forwarded-For", "10.0.0.50")
+	req.RemoteAddr = "192.168.1.100:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.NotContains(t, output, "10.0.0.50")
+	assert.Contains(t, output, "192.168.1.100")
+}
+
 func TestLoggingMiddleware_IncludesUserAgent(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -182,7 +225,7 @@ func TestLoggingMiddleware_CallsNextHandler(t *testing.T) {
 	slog.SetDefault(logger)
 
 	called := false
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -200,7 +243,7 @@ func TestLoggingMiddleware_LogsPath(t *testing.T) {
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -214,12 +257,68 @@ func TestLoggingMiddleware_LogsPath(t *testing.T) {
 	assert.Contains(t, output, "POST")
 }
 
+func TestLoggingMiddleware_SlowRequestLogsWarning(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(1, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(5 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/slow", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "slow_request")
+	assert.Contains(t, output, "/slow")
+}
+
+func TestLoggingMiddleware_FastRequestDoesNotLogWarning(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(1000, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/fast", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.NotContains(t, output, "slow_request")
+}
+
+func TestLoggingMiddleware_ZeroThresholdFallsBackToDefault(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/fast", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.NotContains(t, output, "slow_request", "a quick request should stay under the default 1000ms threshold")
+}
+
 func TestLoggingMiddleware_TwoLogEntries(t *testing.T) {
 	var buf bytes.Buffer
 	logger := slog.New(slog.NewJSONHandler(&buf, nil))
 	slog.SetDefault(logger)
 
-	handler := LoggingMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -235,3 +334,178 @@ func TestLoggingMiddleware_TwoLogEntries(t *testing.T) {
 	assert.Contains(t, lines[0], "request_started")
 	assert.Contains(t, lines[1], "request_completed")
 }
+
+func TestResponseWriter_TracksBytesWrittenAcrossMultipleWrites(t *testing.T) {
+	rec := httptest.NewRecorder()
+	rw := newResponseWriter(rec)
+
+	n1, err := rw.Write([]byte("hello "))
+	assert.NoError(t, err)
+	n2, err := rw.Write([]byte("world"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 6, n1)
+	assert.Equal(t, 5, n2)
+	assert.Equal(t, 11, rw.bytesWritten)
+}
+
+func TestLoggingMiddleware_LogsBytesWritten(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, `"bytes_written":5`)
+}
+
+func TestLoggingMiddleware_LogsQueryString(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/search?search=bitcoin", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "search=bitcoin")
+}
+
+func TestLoggingMiddleware_RedactsSensitiveQueryParams(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, nil, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/login?password=hunter2&token=abc123", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.NotContains(t, output, "hunter2")
+	assert.NotContains(t, output, "abc123")
+	assert.Contains(t, output, "REDACTED")
+}
+
+func TestRedactedQuery_LeavesNonSensitiveParamsUntouched(t *testing.T) {
+	result := redactedQuery("search=bitcoin&page=2")
+
+	assert.Contains(t, result, "search=bitcoin")
+	assert.Contains(t, result, "page=2")
+}
+
+func TestRedactedQuery_RedactsKnownSensitiveKeysCaseInsensitively(t *testing.T) {
+	result := redactedQuery("Password=hunter2&Search=eth")
+
+	assert.NotContains(t, result, "hunter2")
+	assert.Contains(t, result, "REDACTED")
+	assert.Contains(t, result, "Search=eth")
+}
+
+func TestRedactedQuery_EmptyStringReturnsEmpty(t *testing.T) {
+	assert.Equal(t, "", redactedQuery(""))
+}
+
+func TestRedactedQuery_UnparseableQueryReturnedUnchanged(t *testing.T) {
+	malformed := "a=%zz"
+
+	assert.Equal(t, malformed, redactedQuery(malformed))
+}
+
+func TestLoggingMiddleware_SampledPathDroppedWhenSampleRateNearZero(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0.0000001, []string{"/health"}, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "", buf.String(), "a successful denylisted request should be dropped when the sample draw misses")
+}
+
+func TestLoggingMiddleware_SampledPathErrorAlwaysLogged(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0.0000001, []string{"/health"}, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "request_completed")
+	assert.Contains(t, output, "500")
+}
+
+func TestLoggingMiddleware_NonSampledPathAlwaysLoggedRegardlessOfSampleRate(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0.0000001, []string{"/health"}, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/dashboard", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "request_completed")
+	assert.Contains(t, output, "/dashboard")
+}
+
+func TestLoggingMiddleware_ZeroSampleRateFallsBackToLoggingEverything(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	handler := LoggingMiddleware(0, 0, []string{"/health"}, nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/health", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "request_completed", "sample_rate <= 0 should fall back to logging everything")
+}
+
+func TestIsSampledPath_MatchesPrefix(t *testing.T) {
+	assert.True(t, isSampledPath("/health", []string{"/health"}))
+	assert.True(t, isSampledPath("/assets/app.css", []string{"/assets/"}))
+	assert.False(t, isSampledPath("/dashboard", []string{"/health", "/assets/"}))
+}
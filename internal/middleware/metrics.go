@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MetricsRegistry is a dedicated registry for this app's metrics, rather
+// than the global default one, so tests and other packages can't
+// accidentally pollute (or be polluted by) it.
+var MetricsRegistry = prometheus.NewRegistry()
+
+var (
+	requestsTotal = promauto.With(MetricsRegistry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "coinops_http_requests_total",
+			Help: "Total number of HTTP requests, labeled by route, method, and status code.",
+		},
+		[]string{"route", "method", "status"},
+	)
+
+	requestDuration = promauto.With(MetricsRegistry).NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "coinops_http_request_duration_seconds",
+			Help:    "HTTP request latency in seconds, labeled by route and method.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"route", "method"},
+	)
+
+	authSuccessTotal = promauto.With(MetricsRegistry).NewCounter(
+		prometheus.CounterOpts{
+			Name: "coinops_auth_success_total",
+			Help: "Total number of successful login attempts, via the login form or HTTP Basic Auth.",
+		},
+	)
+
+	authFailureTotal = promauto.With(MetricsRegistry).NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "coinops_auth_failure_total",
+			Help: "Total number of failed login attempts, labeled by reason (invalid_username, invalid_password).",
+		},
+		[]string{"reason"},
+	)
+)
+
+// RecordAuthSuccess increments the login-success counter. Always recorded
+// regardless of features.metrics_enabled, since it's cheap and useful for
+// brute-force alerting even when the general HTTP metrics aren't scraped.
+func RecordAuthSuccess() {
+	authSuccessTotal.Inc()
+}
+
+// RecordAuthFailure increments the login-failure counter for reason (e.g.
+// "invalid_username" or "invalid_password"), which surfaces as a label on
+// coinops_auth_failure_total so a spike in one reason can be told apart
+// from the other.
+func RecordAuthFailure(reason string) {
+	authFailureTotal.WithLabelValues(reason).Inc()
+}
+
+// MetricsMiddleware records per-route request counts and latency histograms
+// for Prometheus. It follows the same response-writer-wrapping pattern as
+// LoggingMiddleware. When disabled it's a no-op passthrough, so callers can
+// always include it in the chain and gate behavior with a config flag.
+func MetricsMiddleware(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !enabled {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			wrapped := newResponseWriter(w)
+			next.ServeHTTP(wrapped, r)
+
+			duration := time.Since(start)
+			route := r.URL.Path
+			requestsTotal.WithLabelValues(route, r.Method, strconv.Itoa(wrapped.statusCode)).Inc()
+			requestDuration.WithLabelValues(route, r.Method).Observe(duration.Seconds())
+		})
+	}
+}
+
+// MetricsHandler serves the current metrics in the Prometheus text exposition
+// format from MetricsRegistry.
+func MetricsHandler() http.Handler {
+	return promhttp.HandlerFor(MetricsRegistry, promhttp.HandlerOpts{})
+}
@@ -0,0 +1,108 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsMiddleware_Disabled(t *testing.T) {
+	called := false
+	handler := MetricsMiddleware(false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/disabled-metrics-check", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called)
+	before := testutil.ToFloat64(requestsTotal.WithLabelValues("/disabled-metrics-check", "GET", "200"))
+	assert.Zero(t, before)
+}
+
+func TestMetricsMiddleware_RecordsRequestCount(t *testing.T) {
+	handler := MetricsMiddleware(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/ticker", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(requestsTotal.WithLabelValues("/ticker", "GET", "200")))
+}
+
+func TestMetricsMiddleware_RecordsStatusCode(t *testing.T) {
+	handler := MetricsMiddleware(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+
+	req := httptest.NewRequest("GET", "/missing", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, float64(1), testutil.ToFloat64(requestsTotal.WithLabelValues("/missing", "GET", "404")))
+}
+
+func TestMetricsMiddleware_RecordsDuration(t *testing.T) {
+	handler := MetricsMiddleware(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/duration-check", nil)
+	rec := httptest.NewRecorder()
+
+	before := testutil.CollectAndCount(requestDuration)
+	handler.ServeHTTP(rec, req)
+
+	assert.Greater(t, testutil.CollectAndCount(requestDuration), before)
+}
+
+func TestRecordAuthSuccess_IncrementsCounter(t *testing.T) {
+	before := testutil.ToFloat64(authSuccessTotal)
+
+	RecordAuthSuccess()
+
+	assert.Equal(t, before+1, testutil.ToFloat64(authSuccessTotal))
+}
+
+func TestRecordAuthFailure_IncrementsCounterForReason(t *testing.T) {
+	before := testutil.ToFloat64(authFailureTotal.WithLabelValues("invalid_username"))
+
+	RecordAuthFailure("invalid_username")
+
+	assert.Equal(t, before+1, testutil.ToFloat64(authFailureTotal.WithLabelValues("invalid_username")))
+}
+
+func TestRecordAuthFailure_DistinctReasonsHaveIndependentCounts(t *testing.T) {
+	beforeUsername := testutil.ToFloat64(authFailureTotal.WithLabelValues("invalid_username"))
+	beforePassword := testutil.ToFloat64(authFailureTotal.WithLabelValues("invalid_password"))
+
+	RecordAuthFailure("invalid_password")
+
+	assert.Equal(t, beforeUsername, testutil.ToFloat64(authFailureTotal.WithLabelValues("invalid_username")))
+	assert.Equal(t, beforePassword+1, testutil.ToFloat64(authFailureTotal.WithLabelValues("invalid_password")))
+}
+
+func TestMetricsHandler_ServesPrometheusFormat(t *testing.T) {
+	handler := MetricsMiddleware(true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/metrics-format-check", nil))
+
+	req := httptest.NewRequest("GET", "/metrics", nil)
+	rec := httptest.NewRecorder()
+
+	MetricsHandler().ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), "coinops_http_requests_total")
+}
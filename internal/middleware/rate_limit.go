@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+const (
+	// bucketSweepInterval is how often idle buckets are evicted.
+	bucketSweepInterval = 1 * time.Minute
+	// bucketIdleTTL is how long a client IP's bucket may sit untouched
+	// before it's evicted, so memory doesn't grow unbounded as new IPs
+	// come and go.
+	bucketIdleTTL = 10 * time.Minute
+)
+
+// tokenBucket tracks one client IP's remaining request tokens.
+type tokenBucket struct {
+	tokens   float64
+	updated  time.Time
+	lastSeen time.Time
+}
+
+// RateLimiter is a per-key (typically client IP) token-bucket rate limiter.
+// Tokens refill continuously at requestsPerSecond up to burst capacity; each
+// allowed request consumes one token. A background sweep evicts buckets for
+// keys that have gone idle so memory doesn't grow unbounded.
+type RateLimiter struct {
+	mu       sync.Mutex
+	buckets  map[string]*tokenBucket
+	enabled  bool
+	rps      float64
+	burst    int
+	clock    clockwork.Clock
+	stopChan chan struct{}
+}
+
+// NewRateLimiter creates a RateLimiter. When enabled is false, Allow always
+// returns true and no background sweep is started.
+func NewRateLimiter(clock clockwork.Clock, enabled bool, requestsPerSecond float64, burst int) *RateLimiter {
+	rl := &RateLimiter{
+		buckets:  make(map[string]*tokenBucket),
+		enabled:  enabled,
+		rps:      requestsPerSecond,
+		burst:    burst,
+		clock:    clock,
+		stopChan: make(chan struct{}),
+	}
+
+	if enabled {
+		go rl.sweepIdleBuckets()
+	}
+
+	return rl
+}
+
+// Allow reports whether a request from key may proceed, consuming a token
+// from its bucket if so. Always true when the limiter is disabled.
+func (rl *RateLimiter) Allow(key string) bool {
+	if !rl.enabled {
+		return true
+	}
+
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	now := rl.clock.Now()
+	b, ok := rl.buckets[key]
+	if !ok {
+		b = &tokenBucket{tokens: float64(rl.burst), updated: now}
+		rl.buckets[key] = b
+	}
+
+	elapsed := now.Sub(b.updated).Seconds()
+	b.tokens = math.Min(float64(rl.burst), b.tokens+elapsed*rl.rps)
+	b.updated = now
+	b.lastSeen = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// retryAfterSeconds estimates how long a throttled client should wait before
+// its bucket has refilled by one token.
+func (rl *RateLimiter) retryAfterSeconds() int {
+	if rl.rps <= 0 {
+		return 1
+	}
+	seconds := int(math.Ceil(1 / rl.rps))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// sweepIdleBuckets runs periodically to evict buckets for keys that haven't
+// been seen in a while.
+func (rl *RateLimiter) sweepIdleBuckets() {
+	ticker := rl.clock.NewTicker(bucketSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.Chan():
+			rl.sweep()
+		case <-rl.stopChan:
+			return
+		}
+	}
+}
+
+func (rl *RateLimiter) sweep() {
+	now := rl.clock.Now()
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+
+	for key, b := range rl.buckets {
+		if now.Sub(b.lastSeen) > bucketIdleTTL {
+			delete(rl.buckets, key)
+		}
+	}
+}
+
+// Close stops the background sweep goroutine.
+func (rl *RateLimiter) Close() {
+	if rl.enabled {
+		close(rl.stopChan)
+	}
+}
+
+// RateLimitMiddleware throttles requests per client IP (via getClientIP)
+// using limiter, returning 429 with a Retry-After header once a client's
+// bucket is empty. If authLimiter is non-nil, it's used instead of limiter
+// for requests to authPath, giving login its own stricter bucket to slow
+// brute-force attempts. authPath must already include server.base_path
+// (e.g. via Server.route) so the comparison still matches when the app is
+// hosted under a subpath.
+//
+// trustedProxies and trustForwardedChain scope which peers' forwarded
+// headers the bucket key is drawn from and how a forwarded chain is read -
+// see config.SecurityConfig.TrustedProxies and TrustForwardedChain.
+func RateLimitMiddleware(limiter *RateLimiter, authLimiter *RateLimiter, authPath string, trustedProxies []string, trustForwardedChain bool) func(http.Handler) http.Handler {
+	trusted := parseCIDRs(trustedProxies)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			l := limiter
+			if authLimiter != nil && r.URL.Path == authPath {
+				l = authLimiter
+			}
+
+			ip := getClientIP(r, trusted, trustForwardedChain)
+			if !l.Allow(ip) {
+				w.Header().Set("Retry-After", strconv.Itoa(l.retryAfterSeconds()))
+				http.Error(w, "Too Many Requests", http.StatusTooManyRequests)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
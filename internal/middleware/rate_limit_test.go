@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter_AllowsUpToBurstThenThrottles(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	rl := NewRateLimiter(clock, true, 1, 3)
+	defer rl.Close()
+
+	assert.True(t, rl.Allow("1.2.3.4"))
+	assert.True(t, rl.Allow("1.2.3.4"))
+	assert.True(t, rl.Allow("1.2.3.4"))
+	assert.False(t, rl.Allow("1.2.3.4"), "burst exhausted, next request should be throttled")
+}
+
+func TestRateLimiter_RefillsOverTime(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	rl := NewRateLimiter(clock, true, 1, 1)
+	defer rl.Close()
+
+	require.True(t, rl.Allow("1.2.3.4"))
+	require.False(t, rl.Allow("1.2.3.4"))
+
+	clock.Advance(1 * time.Second)
+	assert.True(t, rl.Allow("1.2.3.4"), "bucket should have refilled by one token")
+}
+
+func TestRateLimiter_TracksBucketsIndependentlyPerKey(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	rl := NewRateLimiter(clock, true, 1, 1)
+	defer rl.Close()
+
+	assert.True(t, rl.Allow("1.2.3.4"))
+	assert.True(t, rl.Allow("5.6.7.8"), "a different key should have its own bucket")
+}
+
+func TestRateLimiter_DisabledAlwaysAllows(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	rl := NewRateLimiter(clock, false, 1, 1)
+	defer rl.Close()
+
+	for i := 0; i < 5; i++ {
+		assert.True(t, rl.Allow("1.2.3.4"))
+	}
+}
+
+func TestRateLimiter_SweepEvictsIdleBuckets(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	rl := NewRateLimiter(clock, true, 1, 1)
+	defer rl.Close()
+
+	rl.Allow("1.2.3.4")
+	require.Len(t, rl.buckets, 1)
+
+	clock.Advance(bucketIdleTTL + time.Second)
+	rl.sweep()
+
+	assert.Empty(t, rl.buckets, "idle bucket should have been evicted")
+}
+
+func TestRateLimiter_CloseIsSafeWhenDisabled(t *testing.T) {
+	rl := NewRateLimiter(clockwork.NewFakeClock(), false, 1, 1)
+	assert.NotPanics(t, func() { rl.Close() })
+}
+
+func TestRateLimitMiddleware_AllowsUnderLimit(t *testing.T) {
+	rl := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 5)
+	defer rl.Close()
+
+	called := false
+	handler := RateLimitMiddleware(rl, nil, "/auth", nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/ticker/bitcoin", nil)
+	req.RemoteAddr = "1.2.3.4:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestRateLimitMiddleware_ReturnsTooManyRequestsWithRetryAfter(t *testing.T) {
+	rl := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 1)
+	defer rl.Close()
+
+	handler := RateLimitMiddleware(rl, nil, "/auth", nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/ticker/bitcoin", nil)
+	req.RemoteAddr = "1.2.3.4:12345"
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code)
+	assert.NotEmpty(t, rec.Header().Get("Retry-After"))
+}
+
+func TestRateLimitMiddleware_UsesAuthLimiterForAuthPath(t *testing.T) {
+	generalLimiter := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 100)
+	authLimiter := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 1)
+	defer generalLimiter.Close()
+	defer authLimiter.Close()
+
+	handler := RateLimitMiddleware(generalLimiter, authLimiter, "/auth", nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/auth", nil)
+	req.RemoteAddr = "1.2.3.4:12345"
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "the stricter auth bucket should be exhausted after one request")
+}
+
+func TestRateLimitMiddleware_UsesAuthLimiterForBasePathPrefixedAuthPath(t *testing.T) {
+	generalLimiter := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 100)
+	authLimiter := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 1)
+	defer generalLimiter.Close()
+	defer authLimiter.Close()
+
+	handler := RateLimitMiddleware(generalLimiter, authLimiter, "/dashboard/auth", []string{"192.0.2.0/24"}, true)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/dashboard/auth", nil)
+	req.RemoteAddr = "1.2.3.4:12345"
+
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rec.Code, "the base-path-prefixed auth route should still hit the stricter auth bucket")
+}
+
+func TestRateLimitMiddleware_UnprefixedAuthPathMissesAuthLimiterUnderBasePath(t *testing.T) {
+	generalLimiter := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 100)
+	authLimiter := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 1)
+	defer generalLimiter.Close()
+	defer authLimiter.Close()
+
+	handler := RateLimitMiddleware(generalLimiter, authLimiter, "/dashboard/auth", nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/auth", nil)
+	req.RemoteAddr = "1.2.3.4:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code, "a request to the unprefixed path should fall through to the general limiter, not the auth one")
+}
+
+func TestRateLimitMiddleware_NilAuthLimiterFallsBackToGeneral(t *testing.T) {
+	rl := NewRateLimiter(clockwork.NewFakeClock(), true, 1, 5)
+	defer rl.Close()
+
+	handler := RateLimitMiddleware(rl, nil, "/auth", nil, false)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("POST", "/auth", nil)
+	req.RemoteAddr = "1.2.3.4:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"context"
+	"log/slog"
 	"net/http"
 
 	"github.com/google/uuid"
@@ -11,10 +12,16 @@ type contextKey string
 
 const RequestIDKey contextKey = "request_id"
 
+const loggerContextKey contextKey = "logger"
+
 // RequestIDMiddleware adds a unique request ID to each request
 // If the client sends an X-Request-ID header, it uses that value
 // Otherwise, it generates a new UUID
 // The request ID is added to both the response header and the request context
+//
+// It also stores a *slog.Logger pre-enriched with that request ID into the
+// context, retrievable via Logger, so handlers get request/access log
+// correlation without threading the ID into every slog call by hand.
 func RequestIDMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// Check if client sent a request ID
@@ -29,6 +36,7 @@ func RequestIDMiddleware(next http.Handler) http.Handler {
 
 		// Add to context for use by other middleware and handlers
 		ctx := context.WithValue(r.Context(), RequestIDKey, id)
+		ctx = context.WithValue(ctx, loggerContextKey, slog.Default().With("request_id", id))
 
 		// Continue with the updated context
 		next.ServeHTTP(w, r.WithContext(ctx))
@@ -43,3 +51,14 @@ func GetRequestID(ctx context.Context) string {
 	}
 	return ""
 }
+
+// Logger returns the *slog.Logger stashed into ctx by RequestIDMiddleware,
+// already enriched with "request_id". Falls back to slog.Default() when
+// ctx has none (e.g. in tests that skip the middleware), so callers never
+// need a nil check.
+func Logger(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerContextKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
@@ -1,7 +1,9 @@
 package middleware
 
 import (
+	"bytes"
 	"context"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -125,3 +127,28 @@ func TestRequestIDMiddleware_EmptyClientID(t *testing.T) {
 	require.NotEmpty(t, respID)
 	assert.Len(t, respID, 36)
 }
+
+func TestRequestIDMiddleware_StoresLoggerWithRequestID(t *testing.T) {
+	var buf bytes.Buffer
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, nil)))
+
+	handler := RequestIDMiddleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Logger(r.Context()).Info("handler_event")
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Request-ID", "req-abc-123")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	output := buf.String()
+	assert.Contains(t, output, "handler_event")
+	assert.Contains(t, output, "req-abc-123")
+}
+
+func TestLogger_FallsBackToDefaultWithoutContextValue(t *testing.T) {
+	logger := Logger(context.Background())
+	assert.NotNil(t, logger)
+}
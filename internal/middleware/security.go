@@ -11,11 +11,11 @@ import (
 	"pahg-template/internal/config"
 )
 
-// IPAllowlistMiddleware restricts access to requests from allowed CIDR ranges
-func IPAllowlistMiddleware(cfg *config.IPAllowlistConfig) func(http.Handler) http.Handler {
-	// Parse CIDR ranges at startup
+// parseCIDRs parses each CIDR in cidrs into a *net.IPNet, logging and
+// skipping any that fail to parse rather than aborting startup.
+func parseCIDRs(cidrs []string) []*net.IPNet {
 	var networks []*net.IPNet
-	for _, cidr := range cfg.CIDRs {
+	for _, cidr := range cidrs {
 		_, network, err := net.ParseCIDR(cidr)
 		if err != nil {
 			slog.Warn("invalid_cidr", "cidr", cidr, "error", err)
@@ -23,8 +23,37 @@ func IPAllowlistMiddleware(cfg *config.IPAllowlistConfig) func(http.Handler) htt
 		}
 		networks = append(networks, network)
 	}
+	return networks
+}
 
-	slog.Info("ip_allowlist_configured", "cidr_count", len(networks), "enabled", cfg.Enabled)
+// ErrorRenderer writes a status/message error response for r, letting a
+// middleware in this package defer to caller-specific formatting (e.g.
+// content-negotiated JSON vs. a themed HTML page) without importing
+// anything from internal/server, which would create an import cycle.
+type ErrorRenderer func(w http.ResponseWriter, r *http.Request, status int, message string)
+
+// IPAllowlistMiddleware restricts access to requests based on cfg.CIDRs.
+// In "allow" mode (the default, used when Mode is unset) only matching IPs
+// pass; in "deny" mode matching IPs are blocked and everything else passes.
+// trustedProxies scopes which peers' X-Forwarded-For/X-Real-IP headers
+// getClientIP will honor, and trustForwardedChain selects how a forwarded
+// chain is read - see config.SecurityConfig.TrustedProxies and
+// TrustForwardedChain. renderer formats the 403 response; if nil, a plain
+// http.Error is written instead.
+func IPAllowlistMiddleware(cfg *config.IPAllowlistConfig, trustedProxies []string, trustForwardedChain bool, renderer ErrorRenderer) func(http.Handler) http.Handler {
+	networks := parseCIDRs(cfg.CIDRs)
+	trusted := parseCIDRs(trustedProxies)
+	denyMode := cfg.Mode == "deny"
+
+	slog.Info("ip_allowlist_configured", "cidr_count", len(networks), "enabled", cfg.Enabled, "mode", cfg.Mode)
+
+	forbidden := func(w http.ResponseWriter, r *http.Request) {
+		if renderer != nil {
+			renderer(w, r, http.StatusForbidden, "Forbidden")
+			return
+		}
+		http.Error(w, "Forbidden", http.StatusForbidden)
+	}
 
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -33,26 +62,31 @@ func IPAllowlistMiddleware(cfg *config.IPAllowlistConfig) func(http.Handler) htt
 				return
 			}
 
-			clientIP := getClientIP(r)
+			clientIP := getClientIP(r, trusted, trustForwardedChain)
 			ip := net.ParseIP(clientIP)
 			if ip == nil {
 				slog.Warn("invalid_client_ip", "ip", clientIP)
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				forbidden(w, r)
 				return
 			}
 
-			// Check if IP is in any allowed network
-			allowed := false
+			matched := false
 			for _, network := range networks {
 				if network.Contains(ip) {
-					allowed = true
+					matched = true
 					break
 				}
 			}
 
-			if !allowed {
+			// deny mode blocks a matching IP; allow mode blocks everything else.
+			blocked := matched
+			if !denyMode {
+				blocked = !matched
+			}
+
+			if blocked {
 				slog.Warn("ip_blocked", "ip", clientIP)
-				http.Error(w, "Forbidden", http.StatusForbidden)
+				forbidden(w, r)
 				return
 			}
 
@@ -109,12 +143,38 @@ func BasicAuthMiddleware(cfg *config.BasicAuthConfig) func(http.Handler) http.Ha
 	}
 }
 
-// getClientIP extracts the client IP from the request
-// Checks X-Forwarded-For, X-Real-IP headers, then falls back to RemoteAddr
-func getClientIP(r *http.Request) string {
+// getClientIP extracts the client IP from the request.
+// X-Forwarded-For/X-Real-IP are only honored when r.RemoteAddr falls within
+// one of trustedProxies - otherwise a direct client could spoof either
+// header to impersonate any IP, bypassing the IP allowlist or polluting
+// access logs. trustedProxies empty means no proxy is trusted, so
+// RemoteAddr is always used.
+//
+// When an X-Forwarded-For chain is present, trustForwardedChain selects how
+// it's read: false (the default) takes the leftmost entry, which a client
+// can freely forge by prepending its own IP before the request ever reaches
+// the proxy; true walks the chain right-to-left and returns the first entry
+// that isn't itself a trusted proxy, the correct client IP behind a known
+// chain of proxies. Only enable it once trustedProxies covers every proxy
+// hop the request actually passes through.
+func getClientIP(r *http.Request, trustedProxies []*net.IPNet, trustForwardedChain bool) string {
+	remoteHost, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// RemoteAddr might not have a port
+		remoteHost = r.RemoteAddr
+	}
+
+	if !isTrustedProxy(remoteHost, trustedProxies) {
+		return remoteHost
+	}
+
 	// Check X-Forwarded-For header (may contain multiple IPs)
 	if xff := r.Header.Get("X-Forwarded-For"); xff != "" {
-		// Take the first IP (original client)
+		if trustForwardedChain {
+			return rightmostUntrustedHop(xff, trustedProxies)
+		}
+		// Take the leftmost IP (the original client, in the common case of
+		// a single trusted proxy)
 		if idx := strings.Index(xff, ","); idx != -1 {
 			return strings.TrimSpace(xff[:idx])
 		}
@@ -126,11 +186,38 @@ func getClientIP(r *http.Request) string {
 		return strings.TrimSpace(xri)
 	}
 
-	// Fall back to RemoteAddr (remove port if present)
-	host, _, err := net.SplitHostPort(r.RemoteAddr)
-	if err != nil {
-		// RemoteAddr might not have a port
-		return r.RemoteAddr
+	return remoteHost
+}
+
+// rightmostUntrustedHop walks a comma-separated X-Forwarded-For chain from
+// right (nearest the server) to left, returning the first entry that isn't
+// a trusted proxy. If every entry is trusted (an atypical but possible
+// deployment), it falls back to the leftmost entry, the original client.
+func rightmostUntrustedHop(xff string, trustedProxies []*net.IPNet) string {
+	hops := strings.Split(xff, ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if !isTrustedProxy(hop, trustedProxies) {
+			return hop
+		}
+	}
+	return strings.TrimSpace(hops[0])
+}
+
+// isTrustedProxy reports whether remoteHost parses as an IP within one of
+// trustedProxies.
+func isTrustedProxy(remoteHost string, trustedProxies []*net.IPNet) bool {
+	if len(trustedProxies) == 0 {
+		return false
+	}
+	ip := net.ParseIP(remoteHost)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
 	}
-	return host
+	return false
 }
@@ -0,0 +1,112 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeadersMiddleware_Disabled(t *testing.T) {
+	handler := SecurityHeadersMiddleware(false, "", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Empty(t, rec.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, rec.Header().Get("X-Frame-Options"))
+	assert.Empty(t, rec.Header().Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeadersMiddleware_SetsBaselineHeaders(t *testing.T) {
+	handler := SecurityHeadersMiddleware(true, "", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "nosniff", rec.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", rec.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "script-src 'nonce-'", rec.Header().Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeadersMiddleware_SubstitutesNonceFromContext(t *testing.T) {
+	handler := CSPNonceMiddleware(SecurityHeadersMiddleware(true, "", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	csp := rec.Header().Get("Content-Security-Policy")
+	assert.NotEqual(t, "script-src 'nonce-'", csp)
+	assert.Regexp(t, `^script-src 'nonce-[\w-]+'$`, csp)
+}
+
+func TestSecurityHeadersMiddleware_CustomPolicyOverridesDefault(t *testing.T) {
+	handler := SecurityHeadersMiddleware(true, "default-src 'self'", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, "default-src 'self'", rec.Header().Get("Content-Security-Policy"))
+}
+
+func TestSecurityHeadersMiddleware_CustomPolicySubstitutesNonceToken(t *testing.T) {
+	handler := CSPNonceMiddleware(SecurityHeadersMiddleware(true, "script-src 'nonce-{{nonce}}' https://cdn.example.com", nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	csp := rec.Header().Get("Content-Security-Policy")
+	assert.Regexp(t, `^script-src 'nonce-[\w-]+' https://cdn\.example\.com$`, csp)
+}
+
+func TestSecurityHeadersMiddleware_HSTSOnlyOverTLS(t *testing.T) {
+	handler := SecurityHeadersMiddleware(true, "", []string{"192.0.2.0/24"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"))
+
+	req = httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.RemoteAddr = "192.0.2.1:1234"
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(t, "max-age=63072000; includeSubDomains", rec.Header().Get("Strict-Transport-Security"))
+}
+
+func TestSecurityHeadersMiddleware_HSTSIgnoresForwardedProtoFromUntrustedClient(t *testing.T) {
+	handler := SecurityHeadersMiddleware(true, "", []string{"192.0.2.0/24"})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.Header.Set("X-Forwarded-Proto", "https")
+	req.RemoteAddr = "203.0.113.5:1234"
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Empty(t, rec.Header().Get("Strict-Transport-Security"), "a spoofed header from an untrusted client must not trigger HSTS")
+}
@@ -18,7 +18,7 @@ func TestIPAllowlistMiddleware_Disabled(t *testing.T) {
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -40,7 +40,7 @@ func TestIPAllowlistMiddleware_AllowsLocalhost(t *testing.T) {
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -62,7 +62,7 @@ func TestIPAllowlistMiddleware_BlocksUnauthorized(t *testing.T) {
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -104,7 +104,7 @@ func TestIPAllowlistMiddleware_AllowsPrivateRanges(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.ip, func(t *testing.T) {
 			called := false
-			handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				called = true
 				w.WriteHeader(http.StatusOK)
 			}))
@@ -138,7 +138,7 @@ func TestIPAllowlistMiddleware_IPv6(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.ip, func(t *testing.T) {
 			called := false
-			handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 				called = true
 				w.WriteHeader(http.StatusOK)
 			}))
@@ -154,21 +154,89 @@ func TestIPAllowlistMiddleware_IPv6(t *testing.T) {
 	}
 }
 
-func TestIPAllowlistMiddleware_XForwardedFor(t *testing.T) {
+func TestIPAllowlistMiddleware_DenyModeBlocksMatchingCIDR(t *testing.T) {
+	cfg := &config.IPAllowlistConfig{
+		Enabled: true,
+		Mode:    "deny",
+		CIDRs:   []string{"8.8.8.8/32"},
+	}
+
+	called := false
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "8.8.8.8:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.False(t, called, "deny mode should block a matching CIDR")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestIPAllowlistMiddleware_DenyModeAllowsNonMatchingCIDR(t *testing.T) {
+	cfg := &config.IPAllowlistConfig{
+		Enabled: true,
+		Mode:    "deny",
+		CIDRs:   []string{"8.8.8.8/32"},
+	}
+
+	called := false
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "1.1.1.1:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called, "deny mode should allow everything not in CIDRs")
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestIPAllowlistMiddleware_AllowModeIsDefaultWhenModeUnset(t *testing.T) {
+	cfg := &config.IPAllowlistConfig{
+		Enabled: true,
+		CIDRs:   []string{"127.0.0.0/8"},
+	}
+
+	called := false
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "8.8.8.8:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.False(t, called, "an unset Mode should behave like allow, blocking non-matching IPs")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
+func TestIPAllowlistMiddleware_XForwardedForFromTrustedProxy(t *testing.T) {
 	cfg := &config.IPAllowlistConfig{
 		Enabled: true,
 		CIDRs:   []string{"10.0.0.0/8"},
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, []string{"8.8.8.8/32"}, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
 
 	req := httptest.NewRequest("GET", "/test", nil)
-	req.RemoteAddr = "8.8.8.8:12345"               // Would be blocked
-	req.Header.Set("X-Forwarded-For", "10.0.0.50") // But X-Forwarded-For is allowed
+	req.RemoteAddr = "8.8.8.8:12345"               // A trusted proxy...
+	req.Header.Set("X-Forwarded-For", "10.0.0.50") // ...so its X-Forwarded-For is honored
 	rec := httptest.NewRecorder()
 
 	handler.ServeHTTP(rec, req)
@@ -177,6 +245,29 @@ func TestIPAllowlistMiddleware_XForwardedFor(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
+func TestIPAllowlistMiddleware_XForwardedForFromUntrustedProxyIgnored(t *testing.T) {
+	cfg := &config.IPAllowlistConfig{
+		Enabled: true,
+		CIDRs:   []string{"10.0.0.0/8"},
+	}
+
+	called := false
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "8.8.8.8:12345"               // Not a trusted proxy
+	req.Header.Set("X-Forwarded-For", "10.0.0.50") // Spoofed header must be ignored
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.False(t, called, "an untrusted peer's spoofed X-Forwarded-For must not bypass the allowlist")
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+}
+
 func TestIPAllowlistMiddleware_XForwardedForMultiple(t *testing.T) {
 	cfg := &config.IPAllowlistConfig{
 		Enabled: true,
@@ -184,7 +275,7 @@ func TestIPAllowlistMiddleware_XForwardedForMultiple(t *testing.T) {
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, []string{"8.8.8.8/32"}, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -201,14 +292,14 @@ func TestIPAllowlistMiddleware_XForwardedForMultiple(t *testing.T) {
 	assert.Equal(t, http.StatusOK, rec.Code)
 }
 
-func TestIPAllowlistMiddleware_XRealIP(t *testing.T) {
+func TestIPAllowlistMiddleware_XRealIPFromTrustedProxy(t *testing.T) {
 	cfg := &config.IPAllowlistConfig{
 		Enabled: true,
 		CIDRs:   []string{"10.0.0.0/8"},
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, []string{"8.8.8.8/32"}, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -231,7 +322,7 @@ func TestIPAllowlistMiddleware_InvalidCIDR(t *testing.T) {
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -253,7 +344,7 @@ func TestIPAllowlistMiddleware_InvalidClientIP(t *testing.T) {
 	}
 
 	called := false
-	handler := IPAllowlistMiddleware(cfg)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	handler := IPAllowlistMiddleware(cfg, nil, false, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		called = true
 		w.WriteHeader(http.StatusOK)
 	}))
@@ -268,6 +359,37 @@ func TestIPAllowlistMiddleware_InvalidClientIP(t *testing.T) {
 	assert.Equal(t, http.StatusForbidden, rec.Code)
 }
 
+func TestIPAllowlistMiddleware_UsesRendererWhenProvided(t *testing.T) {
+	cfg := &config.IPAllowlistConfig{
+		Enabled: true,
+		CIDRs:   []string{"127.0.0.0/8"},
+	}
+
+	var gotStatus int
+	var gotMessage string
+	renderer := func(w http.ResponseWriter, r *http.Request, status int, message string) {
+		gotStatus = status
+		gotMessage = message
+		w.WriteHeader(status)
+		w.Write([]byte("rendered: " + message))
+	}
+
+	handler := IPAllowlistMiddleware(cfg, nil, false, renderer)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "8.8.8.8:12345"
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, http.StatusForbidden, gotStatus)
+	assert.Equal(t, "Forbidden", gotMessage)
+	assert.Equal(t, "rendered: Forbidden", rec.Body.String())
+}
+
 // Basic Auth Tests
 
 func TestBasicAuthMiddleware_Disabled(t *testing.T) {
@@ -432,11 +554,16 @@ func TestBasicAuthMiddleware_NoEnvCredentials(t *testing.T) {
 
 // getClientIP Tests
 
+// trustedTestProxy is a CIDR covering the RemoteAddr used by the getClientIP
+// tests below, so tests that set X-Forwarded-For/X-Real-IP exercise the
+// header-trusted path deliberately, not by default.
+var trustedTestProxy = parseCIDRs([]string{"192.168.1.0/24"})
+
 func TestGetClientIP_RemoteAddr(t *testing.T) {
 	req := httptest.NewRequest("GET", "/test", nil)
 	req.RemoteAddr = "192.168.1.100:12345"
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "192.168.1.100", ip)
 }
@@ -445,7 +572,7 @@ func TestGetClientIP_RemoteAddrNoPort(t *testing.T) {
 	req := httptest.NewRequest("GET", "/test", nil)
 	req.RemoteAddr = "192.168.1.100"
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "192.168.1.100", ip)
 }
@@ -455,7 +582,7 @@ func TestGetClientIP_XForwardedFor(t *testing.T) {
 	req.RemoteAddr = "192.168.1.100:12345"
 	req.Header.Set("X-Forwarded-For", "10.0.0.50")
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "10.0.0.50", ip)
 }
@@ -465,7 +592,7 @@ func TestGetClientIP_XForwardedForMultiple(t *testing.T) {
 	req.RemoteAddr = "192.168.1.100:12345"
 	req.Header.Set("X-Forwarded-For", "10.0.0.50, 172.16.0.1, 8.8.8.8")
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "10.0.0.50", ip)
 }
@@ -475,7 +602,7 @@ func TestGetClientIP_XForwardedForWithSpaces(t *testing.T) {
 	req.RemoteAddr = "192.168.1.100:12345"
 	req.Header.Set("X-Forwarded-For", "  10.0.0.50  ")
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "10.0.0.50", ip)
 }
@@ -485,7 +612,7 @@ func TestGetClientIP_XRealIP(t *testing.T) {
 	req.RemoteAddr = "192.168.1.100:12345"
 	req.Header.Set("X-Real-IP", "10.0.0.50")
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "10.0.0.50", ip)
 }
@@ -496,7 +623,7 @@ func TestGetClientIP_XForwardedForPrecedence(t *testing.T) {
 	req.Header.Set("X-Forwarded-For", "10.0.0.50")
 	req.Header.Set("X-Real-IP", "172.16.0.1")
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	// X-Forwarded-For should take precedence
 	assert.Equal(t, "10.0.0.50", ip)
@@ -506,7 +633,117 @@ func TestGetClientIP_IPv6(t *testing.T) {
 	req := httptest.NewRequest("GET", "/test", nil)
 	req.RemoteAddr = "[::1]:12345"
 
-	ip := getClientIP(req)
+	ip := getClientIP(req, trustedTestProxy, false)
 
 	assert.Equal(t, "::1", ip)
 }
+
+func TestGetClientIP_UntrustedPeerXForwardedForIgnored(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "203.0.113.7:12345" // not in trustedTestProxy
+	req.Header.Set("X-Forwarded-For", "10.0.0.50")
+
+	ip := getClientIP(req, trustedTestProxy, false)
+
+	assert.Equal(t, "203.0.113.7", ip, "a spoofed XFF from an untrusted peer must not override RemoteAddr")
+}
+
+func TestGetClientIP_UntrustedPeerXRealIPIgnored(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "203.0.113.7:12345" // not in trustedTestProxy
+	req.Header.Set("X-Real-IP", "10.0.0.50")
+
+	ip := getClientIP(req, trustedTestProxy, false)
+
+	assert.Equal(t, "203.0.113.7", ip)
+}
+
+func TestGetClientIP_NoTrustedProxiesConfiguredAlwaysUsesRemoteAddr(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.100:12345"
+	req.Header.Set("X-Forwarded-For", "10.0.0.50")
+
+	ip := getClientIP(req, nil, false)
+
+	assert.Equal(t, "192.168.1.100", ip)
+}
+
+func TestGetClientIP_TrustForwardedChainReturnsRightmostUntrustedHop(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.100:12345"
+	// Chain reads client -> proxy A (untrusted) -> proxy B (trusted, our peer).
+	// A client-controlled prefix can forge earlier entries, but not the hop
+	// nearest an actually-trusted proxy, so the rightmost non-trusted entry
+	// is the one that can be believed.
+	req.Header.Set("X-Forwarded-For", "10.0.0.50, 203.0.113.7, 192.168.1.50")
+
+	ip := getClientIP(req, trustedTestProxy, true)
+
+	assert.Equal(t, "203.0.113.7", ip)
+}
+
+func TestGetClientIP_TrustForwardedChainFalseUsesLeftmostInstead(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.100:12345"
+	req.Header.Set("X-Forwarded-For", "10.0.0.50, 203.0.113.7, 192.168.1.50")
+
+	ip := getClientIP(req, trustedTestProxy, false)
+
+	assert.Equal(t, "10.0.0.50", ip, "the default (false) must keep taking the leftmost, spoofable entry")
+}
+
+func TestGetClientIP_TrustForwardedChainAllHopsTrustedFallsBackToLeftmost(t *testing.T) {
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "192.168.1.100:12345"
+	req.Header.Set("X-Forwarded-For", "192.168.1.10, 192.168.1.50")
+
+	ip := getClientIP(req, trustedTestProxy, true)
+
+	assert.Equal(t, "192.168.1.10", ip)
+}
+
+func TestRightmostUntrustedHop_SkipsTrustedTail(t *testing.T) {
+	ip := rightmostUntrustedHop("10.0.0.50, 203.0.113.7, 192.168.1.50", trustedTestProxy)
+
+	assert.Equal(t, "203.0.113.7", ip)
+}
+
+func TestRightmostUntrustedHop_AllTrustedFallsBackToLeftmost(t *testing.T) {
+	ip := rightmostUntrustedHop("192.168.1.10, 192.168.1.50", trustedTestProxy)
+
+	assert.Equal(t, "192.168.1.10", ip)
+}
+
+func TestIPAllowlistMiddleware_TrustForwardedChainUsesRightmostUntrustedHop(t *testing.T) {
+	cfg := &config.IPAllowlistConfig{
+		Enabled: true,
+		CIDRs:   []string{"203.0.113.0/24"},
+	}
+
+	called := false
+	handler := IPAllowlistMiddleware(cfg, []string{"8.8.8.8/32"}, true, nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	req := httptest.NewRequest("GET", "/test", nil)
+	req.RemoteAddr = "8.8.8.8:12345"
+	// The leftmost entry is client-forgeable; only the rightmost untrusted
+	// hop, 203.0.113.7, is the address the trusted proxy actually observed.
+	req.Header.Set("X-Forwarded-For", "10.0.0.50, 203.0.113.7, 8.8.8.8")
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	assert.True(t, called)
+	assert.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestIsTrustedProxy_MatchesWithinRange(t *testing.T) {
+	assert.True(t, isTrustedProxy("192.168.1.50", trustedTestProxy))
+	assert.False(t, isTrustedProxy("203.0.113.7", trustedTestProxy))
+}
+
+func TestIsTrustedProxy_EmptyListTrustsNothing(t *testing.T) {
+	assert.False(t, isTrustedProxy("192.168.1.50", nil))
+}
@@ -5,12 +5,27 @@ import (
 	"time"
 )
 
+// defaultMaxTitleLen and defaultMaxMessageLen are used when a Store isn't
+// given explicit limits via SetMaxLengths, so title/message are always
+// bounded even by default.
+const (
+	defaultMaxTitleLen   = 100
+	defaultMaxMessageLen = 500
+	// defaultMaxSize is used when a Store isn't given an explicit cap via
+	// SetMaxSize, bounding memory use on a long-running server.
+	defaultMaxSize = 100
+)
+
+// ellipsis is appended to a title/message truncated by Add.
+const ellipsis = "..."
+
 // Notification represents a single notification
 type Notification struct {
 	ID        int       `json:"id"`
 	Title     string    `json:"title"`
 	Message   string    `json:"message"`
 	Timestamp time.Time `json:"timestamp"`
+	Read      bool      `json:"read"`
 }
 
 // Store is a thread-safe in-memory notification store
@@ -18,6 +33,9 @@ type Store struct {
 	mu            sync.RWMutex
 	notifications []Notification
 	nextID        int
+	maxTitleLen   int
+	maxMessageLen int
+	maxSize       int
 }
 
 // NewStore creates a new notification store
@@ -25,26 +43,83 @@ func NewStore() *Store {
 	return &Store{
 		notifications: make([]Notification, 0),
 		nextID:        1,
+		maxTitleLen:   defaultMaxTitleLen,
+		maxMessageLen: defaultMaxMessageLen,
+		maxSize:       defaultMaxSize,
 	}
 }
 
-// Add creates a new notification
+// SetMaxSize changes how many notifications the store keeps. Once exceeded,
+// Add drops the oldest entries. A zero or negative max falls back to
+// defaultMaxSize rather than disabling the cap.
+func (s *Store) SetMaxSize(max int) {
+	if max <= 0 {
+		max = defaultMaxSize
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxSize = max
+	s.evictOldestLocked()
+}
+
+// SetMaxLengths changes the title/message length Add truncates to. A zero or
+// negative maxTitle/maxMessage falls back to defaultMaxTitleLen/
+// defaultMaxMessageLen respectively, rather than disabling truncation.
+func (s *Store) SetMaxLengths(maxTitle, maxMessage int) {
+	if maxTitle <= 0 {
+		maxTitle = defaultMaxTitleLen
+	}
+	if maxMessage <= 0 {
+		maxMessage = defaultMaxMessageLen
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.maxTitleLen = maxTitle
+	s.maxMessageLen = maxMessage
+}
+
+// Add creates a new notification, truncating title/message (with an
+// ellipsis) if they exceed the store's configured maxima, so a single huge
+// notification can't bloat memory or the notifications modal.
 func (s *Store) Add(title, message string) Notification {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	n := Notification{
 		ID:        s.nextID,
-		Title:     title,
-		Message:   message,
+		Title:     truncate(title, s.maxTitleLen),
+		Message:   truncate(message, s.maxMessageLen),
 		Timestamp: time.Now(),
 	}
 	s.nextID++
 
 	s.notifications = append(s.notifications, n)
+	s.evictOldestLocked()
 	return n
 }
 
+// evictOldestLocked drops the oldest notifications until the store is back
+// within maxSize. Callers must hold s.mu.
+func (s *Store) evictOldestLocked() {
+	if excess := len(s.notifications) - s.maxSize; excess > 0 {
+		s.notifications = s.notifications[excess:]
+	}
+}
+
+// truncate shortens s to at most max runes, replacing the tail with an
+// ellipsis if it was cut. Runes (not bytes) are counted so multi-byte
+// characters aren't split.
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	if max <= len(ellipsis) {
+		return string(runes[:max])
+	}
+	return string(runes[:max-len(ellipsis)]) + ellipsis
+}
+
 // GetAll returns all notifications (newest first)
 func (s *Store) GetAll() []Notification {
 	s.mu.RLock()
@@ -71,3 +146,52 @@ func (s *Store) Clear() {
 	defer s.mu.Unlock()
 	s.notifications = make([]Notification, 0)
 }
+
+// Delete removes the notification with the given ID, reporting whether one
+// existed to remove.
+func (s *Store) Delete(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		if s.notifications[i].ID == id {
+			s.notifications = append(s.notifications[:i], s.notifications[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// MarkAllRead marks every notification as read.
+func (s *Store) MarkAllRead() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		s.notifications[i].Read = true
+	}
+}
+
+// MarkRead marks the notification with the given ID as read. It's a no-op if
+// no notification with that ID exists (e.g. it was already evicted).
+func (s *Store) MarkRead(id int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.notifications {
+		if s.notifications[i].ID == id {
+			s.notifications[i].Read = true
+			return
+		}
+	}
+}
+
+// UnreadCount returns the number of notifications that haven't been marked read.
+func (s *Store) UnreadCount() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	count := 0
+	for _, n := range s.notifications {
+		if !n.Read {
+			count++
+		}
+	}
+	return count
+}
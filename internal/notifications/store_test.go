@@ -1,6 +1,7 @@
 package notifications
 
 import (
+	"strings"
 	"sync"
 	"testing"
 	"time"
@@ -213,6 +214,64 @@ func TestNotification_JSONTags(t *testing.T) {
 	assert.False(t, n.Timestamp.IsZero())
 }
 
+func TestStore_Add_ShortTitleAndMessageUntouched(t *testing.T) {
+	store := NewStore()
+	store.SetMaxLengths(10, 20)
+
+	n := store.Add("short", "also short")
+
+	assert.Equal(t, "short", n.Title)
+	assert.Equal(t, "also short", n.Message)
+}
+
+func TestStore_Add_TitleAtLimitUntouched(t *testing.T) {
+	store := NewStore()
+	store.SetMaxLengths(10, 500)
+
+	title := strings.Repeat("a", 10)
+	n := store.Add(title, "message")
+
+	assert.Equal(t, title, n.Title)
+}
+
+func TestStore_Add_TruncatesTitleOverLimit(t *testing.T) {
+	store := NewStore()
+	store.SetMaxLengths(10, 500)
+
+	n := store.Add(strings.Repeat("a", 11), "message")
+
+	assert.Equal(t, strings.Repeat("a", 7)+"...", n.Title)
+	assert.Len(t, n.Title, 10)
+}
+
+func TestStore_Add_TruncatesMessageOverLimit(t *testing.T) {
+	store := NewStore()
+	store.SetMaxLengths(100, 20)
+
+	n := store.Add("title", strings.Repeat("b", 25))
+
+	assert.Equal(t, strings.Repeat("b", 17)+"...", n.Message)
+	assert.Len(t, n.Message, 20)
+}
+
+func TestStore_SetMaxLengths_NonPositiveFallsBackToDefaults(t *testing.T) {
+	store := NewStore()
+
+	store.SetMaxLengths(0, -1)
+
+	assert.Equal(t, defaultMaxTitleLen, store.maxTitleLen)
+	assert.Equal(t, defaultMaxMessageLen, store.maxMessageLen)
+}
+
+func TestStore_Add_DefaultLimitsApplyWithoutSetMaxLengths(t *testing.T) {
+	store := NewStore()
+
+	n := store.Add(strings.Repeat("a", defaultMaxTitleLen+10), "short")
+
+	assert.Len(t, n.Title, defaultMaxTitleLen)
+	assert.True(t, strings.HasSuffix(n.Title, "..."))
+}
+
 func TestStore_Add_TimestampIsRecent(t *testing.T) {
 	store := NewStore()
 
@@ -223,3 +282,122 @@ func TestStore_Add_TimestampIsRecent(t *testing.T) {
 	assert.True(t, n.Timestamp.After(before) || n.Timestamp.Equal(before))
 	assert.True(t, n.Timestamp.Before(after) || n.Timestamp.Equal(after))
 }
+
+func TestStore_SetMaxSize_NonPositiveFallsBackToDefault(t *testing.T) {
+	store := NewStore()
+
+	store.SetMaxSize(0)
+
+	assert.Equal(t, defaultMaxSize, store.maxSize)
+}
+
+func TestStore_Add_BeyondCapKeepsOnlyMostRecentN(t *testing.T) {
+	store := NewStore()
+	store.SetMaxSize(3)
+
+	store.Add("first", "")
+	store.Add("second", "")
+	store.Add("third", "")
+	store.Add("fourth", "")
+	store.Add("fifth", "")
+
+	assert.Equal(t, 3, store.Count())
+
+	all := store.GetAll()
+	require.Len(t, all, 3)
+	assert.Equal(t, "fifth", all[0].Title)
+	assert.Equal(t, "fourth", all[1].Title)
+	assert.Equal(t, "third", all[2].Title)
+}
+
+func TestStore_Delete(t *testing.T) {
+	store := NewStore()
+	n1 := store.Add("first", "")
+	store.Add("second", "")
+
+	deleted := store.Delete(n1.ID)
+
+	assert.True(t, deleted)
+	assert.Equal(t, 1, store.Count())
+	all := store.GetAll()
+	require.Len(t, all, 1)
+	assert.Equal(t, "second", all[0].Title)
+}
+
+func TestStore_Delete_UnknownIDReturnsFalse(t *testing.T) {
+	store := NewStore()
+	store.Add("first", "")
+
+	deleted := store.Delete(999)
+
+	assert.False(t, deleted)
+	assert.Equal(t, 1, store.Count())
+}
+
+func TestStore_MarkAllRead(t *testing.T) {
+	store := NewStore()
+	store.Add("first", "")
+	store.Add("second", "")
+
+	assert.Equal(t, 2, store.UnreadCount())
+
+	store.MarkAllRead()
+
+	assert.Equal(t, 0, store.UnreadCount())
+	for _, n := range store.GetAll() {
+		assert.True(t, n.Read)
+	}
+}
+
+func TestStore_MarkRead(t *testing.T) {
+	store := NewStore()
+	n1 := store.Add("first", "")
+	store.Add("second", "")
+
+	store.MarkRead(n1.ID)
+
+	assert.Equal(t, 1, store.UnreadCount())
+	all := store.GetAll()
+	require.Len(t, all, 2)
+	for _, n := range all {
+		if n.ID == n1.ID {
+			assert.True(t, n.Read)
+		} else {
+			assert.False(t, n.Read)
+		}
+	}
+}
+
+func TestStore_MarkRead_UnknownIDIsNoop(t *testing.T) {
+	store := NewStore()
+	store.Add("first", "")
+
+	store.MarkRead(999)
+
+	assert.Equal(t, 1, store.UnreadCount())
+}
+
+func TestStore_UnreadCount_NewNotificationsAreUnread(t *testing.T) {
+	store := NewStore()
+
+	assert.Equal(t, 0, store.UnreadCount())
+
+	store.Add("first", "")
+	store.Add("second", "")
+
+	assert.Equal(t, 2, store.UnreadCount())
+}
+
+func TestStore_SetMaxSize_ShrinkingEvictsExcessImmediately(t *testing.T) {
+	store := NewStore()
+	store.Add("first", "")
+	store.Add("second", "")
+	store.Add("third", "")
+
+	store.SetMaxSize(1)
+
+	assert.Equal(t, 1, store.Count())
+	all := store.GetAll()
+	require.Len(t, all, 1)
+	assert.Equal(t, "third", all[0].Title)
+}
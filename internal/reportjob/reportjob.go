@@ -0,0 +1,130 @@
+// Package reportjob tracks asynchronous report-generation jobs, so a client
+// that kicked one off can poll for its result instead of holding a
+// connection open for the duration of the work.
+package reportjob
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+)
+
+// Status is a job's lifecycle state.
+type Status string
+
+const (
+	StatusPending Status = "pending"
+	StatusDone    Status = "done"
+)
+
+// jobIDLength is the number of random bytes used to generate a job ID.
+const jobIDLength = 16
+
+// Job is a snapshot of a single tracked report-generation job.
+type Job struct {
+	ID     string
+	Status Status
+	// Timestamp is the generated report's timestamp, set once Status is
+	// StatusDone.
+	Timestamp string
+}
+
+// entry is a Job plus its internal expiry, kept out of the Job snapshot
+// callers see.
+type entry struct {
+	job       Job
+	expiresAt time.Time
+}
+
+// Store is a thread-safe in-memory map of report generation jobs. Entries
+// are swept once their TTL passes, so memory doesn't grow unbounded as jobs
+// are created.
+type Store struct {
+	mu      sync.Mutex
+	clock   clockwork.Clock
+	ttl     time.Duration
+	entries map[string]*entry
+}
+
+// NewStore creates a Store whose entries expire ttl after being created.
+func NewStore(ttl time.Duration) *Store {
+	return NewStoreWithClock(ttl, clockwork.NewRealClock())
+}
+
+// NewStoreWithClock is like NewStore but takes an explicit clock, so tests
+// can control expiry deterministically.
+func NewStoreWithClock(ttl time.Duration, clock clockwork.Clock) *Store {
+	return &Store{
+		clock:   clock,
+		ttl:     ttl,
+		entries: make(map[string]*entry),
+	}
+}
+
+// Create starts a new pending job and returns its ID.
+func (s *Store) Create() (string, error) {
+	id, err := generateJobID()
+	if err != nil {
+		return "", err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock.Now()
+	s.entries[id] = &entry{
+		job:       Job{ID: id, Status: StatusPending},
+		expiresAt: now.Add(s.ttl),
+	}
+	s.sweepLocked(now)
+
+	return id, nil
+}
+
+// Complete marks id's job done with the given result timestamp. It's a
+// no-op if the job doesn't exist, e.g. it already expired.
+func (s *Store) Complete(id, timestamp string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	e.job.Status = StatusDone
+	e.job.Timestamp = timestamp
+}
+
+// Get returns a snapshot of id's job and whether it was found (and hasn't
+// expired).
+func (s *Store) Get(id string) (Job, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[id]
+	if !ok || !s.clock.Now().Before(e.expiresAt) {
+		return Job{}, false
+	}
+	return e.job, true
+}
+
+// sweepLocked drops expired entries. Callers must hold s.mu.
+func (s *Store) sweepLocked(now time.Time) {
+	for id, e := range s.entries {
+		if !now.Before(e.expiresAt) {
+			delete(s.entries, id)
+		}
+	}
+}
+
+// generateJobID creates a cryptographically random job ID.
+func generateJobID() (string, error) {
+	b := make([]byte, jobIDLength)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,90 @@
+package reportjob
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Create_StartsPending(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	id, err := store.Create()
+	require.NoError(t, err)
+	require.NotEmpty(t, id)
+
+	job, ok := store.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, StatusPending, job.Status)
+	assert.Empty(t, job.Timestamp)
+}
+
+func TestStore_Complete_MarksDone(t *testing.T) {
+	store := NewStore(time.Minute)
+	id, err := store.Create()
+	require.NoError(t, err)
+
+	store.Complete(id, "20250101_000000")
+
+	job, ok := store.Get(id)
+	require.True(t, ok)
+	assert.Equal(t, StatusDone, job.Status)
+	assert.Equal(t, "20250101_000000", job.Timestamp)
+}
+
+func TestStore_Complete_UnknownIDIsNoop(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	store.Complete("nonexistent", "20250101_000000")
+}
+
+func TestStore_Get_UnknownIDReturnsFalse(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	_, ok := store.Get("nonexistent")
+	assert.False(t, ok)
+}
+
+func TestStore_Get_ExpiredReturnsFalse(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(time.Minute, clock)
+
+	id, err := store.Create()
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Minute)
+
+	_, ok := store.Get(id)
+	assert.False(t, ok)
+}
+
+func TestStore_Create_SweepsExpiredEntries(t *testing.T) {
+	clock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(time.Minute, clock)
+
+	firstID, err := store.Create()
+	require.NoError(t, err)
+
+	clock.Advance(2 * time.Minute)
+
+	_, err = store.Create()
+	require.NoError(t, err)
+
+	assert.Len(t, store.entries, 1)
+	_, ok := store.entries[firstID]
+	assert.False(t, ok)
+}
+
+func TestStore_Create_GeneratesUniqueIDs(t *testing.T) {
+	store := NewStore(time.Minute)
+
+	id1, err := store.Create()
+	require.NoError(t, err)
+	id2, err := store.Create()
+	require.NoError(t, err)
+
+	assert.NotEqual(t, id1, id2)
+}
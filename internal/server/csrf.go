@@ -0,0 +1,83 @@
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"net/http"
+
+	"pahg-template/internal/middleware"
+)
+
+// csrfCookieName is the cookie holding the server's half of the double-submit
+// CSRF token. It's HttpOnly - the token value reaches the browser via
+// template data instead, following the same pattern as window.BASE_PATH.
+const csrfCookieName = "coinops_csrf"
+
+// csrfHeaderName is the header form-based clients can't use (HTMX requests)
+// submit their CSRF token in, as an alternative to the csrf_token form field.
+const csrfHeaderName = "X-CSRF-Token"
+
+// csrfTokenLength matches session.sessionIDLength - there's no reason for
+// these to differ, but they're independent constants since a CSRF token and a
+// session ID protect different things.
+const csrfTokenLength = 32
+
+// generateCSRFToken creates a cryptographically secure random CSRF token.
+func generateCSRFToken() (string, error) {
+	bytes := make([]byte, csrfTokenLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(bytes), nil
+}
+
+// csrfToken returns the request's current CSRF token, minting one and
+// setting its cookie if it doesn't have one yet. Handlers that render a form
+// or HTMX page call this to get the value to embed in the response, so the
+// token the client submits later can be checked against the cookie by
+// validateCSRFToken.
+func (s *Server) csrfToken(w http.ResponseWriter, r *http.Request) (string, error) {
+	if cookie, err := r.Cookie(csrfCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value, nil
+	}
+
+	token, err := generateCSRFToken()
+	if err != nil {
+		return "", err
+	}
+
+	secure := middleware.IsSecureRequest(r, s.config().Security.TrustedProxies)
+	http.SetCookie(w, &http.Cookie{
+		Name:     csrfCookieName,
+		Value:    token,
+		Path:     s.route("/"),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return token, nil
+}
+
+// validateCSRFToken reports whether r carries a CSRF token matching its
+// csrfCookieName cookie. The submitted token may come from the X-CSRF-Token
+// header (HTMX requests, which set it via hx-headers) or a csrf_token form
+// field (the login form). Both a missing cookie and a missing/mismatched
+// submitted token fail validation.
+func (s *Server) validateCSRFToken(r *http.Request) bool {
+	cookie, err := r.Cookie(csrfCookieName)
+	if err != nil || cookie.Value == "" {
+		return false
+	}
+
+	submitted := r.Header.Get(csrfHeaderName)
+	if submitted == "" {
+		submitted = r.FormValue("csrf_token")
+	}
+	if submitted == "" {
+		return false
+	}
+
+	return subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(submitted)) == 1
+}
@@ -1,7 +1,11 @@
 package server
 
 import (
+	"context"
+	"time"
+
 	"pahg-template/internal/coingecko"
+	"pahg-template/internal/config"
 	"pahg-template/internal/notifications"
 	"pahg-template/internal/session"
 )
@@ -10,15 +14,28 @@ import (
 type CoinService interface {
 	GetPrices() ([]coingecko.Coin, error)
 	GetCoin(id string) (*coingecko.Coin, error)
+	RefreshCoin(id string) (*coingecko.Coin, error)
+	GetHistory(coinID string, days int) ([]coingecko.PricePoint, error)
 	SearchCoins(query string) ([]coingecko.Coin, error)
+	Changes(since int64) ([]coingecko.Coin, int64, error)
+	CacheStats() (size int, ageSeconds float64)
+	Ping(ctx context.Context) error
+	ValidateVsCurrency(ctx context.Context) error
+	SetCoins(coins []config.CoinConfig)
+	IsRateLimited() bool
+	RateLimitRetryAfter() time.Duration
+	PortfolioValue() coingecko.PortfolioValue
 }
 
 // SessionStore defines the interface for session management
 type SessionStore interface {
 	Create(username string) (*session.Session, error)
 	Get(sessionID string) *session.Session
+	Touch(sessionID string) (expiresAt time.Time, renewed bool)
 	Delete(sessionID string)
+	DeleteByUsername(username string) int
 	Count() int
+	Summaries() []session.SessionSummary
 	Close()
 }
 
@@ -28,4 +45,8 @@ type NotificationStore interface {
 	GetAll() []notifications.Notification
 	Count() int
 	Clear()
+	Delete(id int) bool
+	MarkAllRead()
+	MarkRead(id int)
+	UnreadCount() int
 }
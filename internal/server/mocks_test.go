@@ -1,17 +1,35 @@
 package server
 
 import (
+	"context"
+	"time"
+
 	"pahg-template/internal/coingecko"
+	"pahg-template/internal/config"
 	"pahg-template/internal/notifications"
 	"pahg-template/internal/session"
 )
 
 // MockCoinService is a mock implementation of CoinService for testing
 type MockCoinService struct {
-	Coins        []coingecko.Coin
-	GetPricesErr error
-	GetCoinErr   error
-	SearchErr    error
+	Coins                 []coingecko.Coin
+	SetCoinsCalls         [][]config.CoinConfig
+	GetPricesErr          error
+	GetCoinErr            error
+	RefreshCoinErr        error
+	SearchErr             error
+	PingErr               error
+	ValidateVsCurrencyErr error
+	ChangesErr            error
+	ChangesCoins          []coingecko.Coin
+	ChangesGen            int64
+	CacheStatsSize        int
+	CacheStatsAgeSecs     float64
+	HistoryPoints         []coingecko.PricePoint
+	HistoryErr            error
+	RateLimited           bool
+	RetryAfter            time.Duration
+	Portfolio             coingecko.PortfolioValue
 }
 
 func (m *MockCoinService) GetPrices() ([]coingecko.Coin, error) {
@@ -33,6 +51,25 @@ func (m *MockCoinService) GetCoin(id string) (*coingecko.Coin, error) {
 	return nil, coingecko.ErrCoinNotFound
 }
 
+func (m *MockCoinService) RefreshCoin(id string) (*coingecko.Coin, error) {
+	if m.RefreshCoinErr != nil {
+		return nil, m.RefreshCoinErr
+	}
+	for _, coin := range m.Coins {
+		if coin.ID == id {
+			return &coin, nil
+		}
+	}
+	return nil, coingecko.ErrCoinNotFound
+}
+
+func (m *MockCoinService) GetHistory(coinID string, days int) ([]coingecko.PricePoint, error) {
+	if m.HistoryErr != nil {
+		return nil, m.HistoryErr
+	}
+	return m.HistoryPoints, nil
+}
+
 func (m *MockCoinService) SearchCoins(query string) ([]coingecko.Coin, error) {
 	if m.SearchErr != nil {
 		return nil, m.SearchErr
@@ -49,6 +86,41 @@ func (m *MockCoinService) SearchCoins(query string) ([]coingecko.Coin, error) {
 	return results, nil
 }
 
+func (m *MockCoinService) Changes(since int64) ([]coingecko.Coin, int64, error) {
+	if m.ChangesErr != nil {
+		return nil, 0, m.ChangesErr
+	}
+	return m.ChangesCoins, m.ChangesGen, nil
+}
+
+func (m *MockCoinService) CacheStats() (size int, ageSeconds float64) {
+	return m.CacheStatsSize, m.CacheStatsAgeSecs
+}
+
+func (m *MockCoinService) Ping(ctx context.Context) error {
+	return m.PingErr
+}
+
+func (m *MockCoinService) ValidateVsCurrency(ctx context.Context) error {
+	return m.ValidateVsCurrencyErr
+}
+
+func (m *MockCoinService) SetCoins(coins []config.CoinConfig) {
+	m.SetCoinsCalls = append(m.SetCoinsCalls, coins)
+}
+
+func (m *MockCoinService) IsRateLimited() bool {
+	return m.RateLimited
+}
+
+func (m *MockCoinService) RateLimitRetryAfter() time.Duration {
+	return m.RetryAfter
+}
+
+func (m *MockCoinService) PortfolioValue() coingecko.PortfolioValue {
+	return m.Portfolio
+}
+
 func contains(s, substr string) bool {
 	return len(s) > 0 && len(substr) > 0 &&
 		(s == substr || (len(s) > len(substr) && (s[:len(substr)] == substr || s[len(s)-len(substr):] == substr)))
@@ -84,14 +156,45 @@ func (m *MockSessionStore) Get(sessionID string) *session.Session {
 	return m.Sessions[sessionID]
 }
 
+func (m *MockSessionStore) Touch(sessionID string) (time.Time, bool) {
+	sess, ok := m.Sessions[sessionID]
+	if !ok {
+		return time.Time{}, false
+	}
+	return sess.ExpiresAt, false
+}
+
 func (m *MockSessionStore) Delete(sessionID string) {
 	delete(m.Sessions, sessionID)
 }
 
+func (m *MockSessionStore) DeleteByUsername(username string) int {
+	count := 0
+	for id, sess := range m.Sessions {
+		if sess.Username == username {
+			delete(m.Sessions, id)
+			count++
+		}
+	}
+	return count
+}
+
 func (m *MockSessionStore) Count() int {
 	return len(m.Sessions)
 }
 
+func (m *MockSessionStore) Summaries() []session.SessionSummary {
+	summaries := make([]session.SessionSummary, 0, len(m.Sessions))
+	for _, sess := range m.Sessions {
+		summaries = append(summaries, session.SessionSummary{
+			Username:  sess.Username,
+			CreatedAt: sess.CreatedAt,
+			ExpiresAt: sess.ExpiresAt,
+		})
+	}
+	return summaries
+}
+
 func (m *MockSessionStore) Close() {}
 
 // MockNotificationStore is a mock implementation of NotificationStore for testing
@@ -134,3 +237,38 @@ func (m *MockNotificationStore) Count() int {
 func (m *MockNotificationStore) Clear() {
 	m.Notifications = []notifications.Notification{}
 }
+
+func (m *MockNotificationStore) Delete(id int) bool {
+	for i := range m.Notifications {
+		if m.Notifications[i].ID == id {
+			m.Notifications = append(m.Notifications[:i], m.Notifications[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (m *MockNotificationStore) MarkAllRead() {
+	for i := range m.Notifications {
+		m.Notifications[i].Read = true
+	}
+}
+
+func (m *MockNotificationStore) MarkRead(id int) {
+	for i := range m.Notifications {
+		if m.Notifications[i].ID == id {
+			m.Notifications[i].Read = true
+			return
+		}
+	}
+}
+
+func (m *MockNotificationStore) UnreadCount() int {
+	count := 0
+	for _, n := range m.Notifications {
+		if !n.Read {
+			count++
+		}
+	}
+	return count
+}
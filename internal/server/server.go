@@ -1,25 +1,44 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"embed"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"html/template"
+	"io"
 	"io/fs"
 	"log/slog"
+	"math"
+	"net"
 	"net/http"
+	"net/http/pprof"
+	"net/url"
 	"os"
+	"reflect"
 	"runtime"
+	"slices"
+	"sort"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/jonboulle/clockwork"
 	"golang.org/x/crypto/bcrypt"
 
+	"pahg-template/internal/announcement"
+	"pahg-template/internal/audit"
 	"pahg-template/internal/coingecko"
 	"pahg-template/internal/config"
+	"pahg-template/internal/idempotency"
 	pmath "pahg-template/internal/math"
 	"pahg-template/internal/middleware"
 	"pahg-template/internal/notifications"
+	"pahg-template/internal/reportjob"
 	"pahg-template/internal/session"
 	"pahg-template/internal/version"
 )
@@ -32,105 +51,551 @@ var assetsFS embed.FS
 
 // Server holds all dependencies for the HTTP server
 type Server struct {
-	cfg           *config.Config
-	templates     *template.Template
-	coinService   CoinService
-	notifications NotificationStore
-	sessions      SessionStore
-	mux           *http.ServeMux
-	startTime     time.Time
-}
-
-// Template functions
-var funcMap = template.FuncMap{
-	"json": func(v interface{}) template.JS {
-		b, _ := json.Marshal(v)
-		return template.JS(b)
-	},
-	// assetURL appends the git commit hash to asset URLs for cache busting.
-	// This ensures browsers fetch new versions when the application is deployed.
-	"assetURL": func(path string) string {
-		commit := version.Get().Commit
-		if commit == "" || commit == "unknown" {
-			return path
-		}
-		return fmt.Sprintf("%s?v=%s", path, commit)
-	},
+	// cfgMu guards cfg, which ReloadConfig swaps wholesale in response to a
+	// SIGHUP-triggered config reload (see cmd/coinops/serve.go), so handlers
+	// running concurrently with a reload see either the old config or the
+	// new one, never a torn read.
+	cfgMu             sync.RWMutex
+	cfg               *config.Config
+	templates         *template.Template
+	coinService       CoinService
+	notifications     NotificationStore
+	audit             *audit.Store
+	sessions          SessionStore
+	mux               *http.ServeMux
+	startTime         time.Time
+	apiHealth         apiHealthCache
+	rateLimiter       *middleware.RateLimiter
+	authRateLimiter   *middleware.RateLimiter
+	coinRefreshMs     map[string]int
+	alertsDisabled    map[string]bool
+	pinnedCoins       map[string]bool
+	configSource      string
+	canaryTrusted     []*net.IPNet
+	announcements     *announcement.Store
+	reportIdempotency *idempotency.Store
+	reportJobs        *reportjob.Store
+	delayGenMu        sync.Mutex
+	delayGen          *pmath.Generator
+	routes            []string
+	draining          chan struct{}
+	drainOnce         sync.Once
+	// shutdownCtx is canceled by Close, so background work that must outlive
+	// the request that started it (e.g. runReportJob) still stops at process
+	// shutdown instead of leaking past it.
+	shutdownCtx    context.Context
+	cancelShutdown context.CancelFunc
+}
+
+// apiHealthCacheTTL controls how long a verbose /api/health result is
+// reused before the dependency checks are re-run.
+const apiHealthCacheTTL = 5 * time.Second
+
+// apiHealthCheckTimeout bounds each individual dependency check.
+const apiHealthCheckTimeout = 2 * time.Second
+
+// apiHealthCache memoizes the last verbose health check result so repeated
+// polling doesn't hammer upstream dependencies.
+type apiHealthCache struct {
+	mu        sync.Mutex
+	result    APIHealthResponse
+	checkedAt time.Time
+}
+
+// withBasePath prepends base to path, so routes and generated URLs work when
+// the app is hosted behind a reverse proxy under a subpath (e.g. "/coinops").
+// An empty base is a no-op, giving root hosting unchanged.
+func withBasePath(base, path string) string {
+	return base + path
+}
+
+// baseFuncMap builds the template functions that depend on a server's
+// config: "url" prefixes an in-app path with server.base_path, and
+// "assetURL" does the same before appending the git commit hash for cache
+// busting.
+func baseFuncMap(cfg *config.Config) template.FuncMap {
+	return template.FuncMap{
+		"json": func(v interface{}) template.JS {
+			b, _ := json.Marshal(v)
+			return template.JS(b)
+		},
+		"url": func(path string) string {
+			return withBasePath(cfg.Server.BasePath, path)
+		},
+		// assetURL prefixes path with server.base_path and appends the git
+		// commit hash for cache busting, so browsers fetch new versions when
+		// the application is deployed.
+		"assetURL": func(path string) string {
+			prefixed := withBasePath(cfg.Server.BasePath, path)
+			commit := version.Get().Commit
+			if commit == "" || commit == "unknown" {
+				return prefixed
+			}
+			return fmt.Sprintf("%s?v=%s", prefixed, commit)
+		},
+	}
+}
+
+// currencyDisplayDefaults gives a sensible symbol and position for common
+// currency codes when features.currency_symbol/currency_symbol_position
+// aren't set explicitly.
+var currencyDisplayDefaults = map[string]struct {
+	symbol   string
+	position string
+}{
+	"usd": {"$", "prefix"},
+	"gbp": {"£", "prefix"},
+	"jpy": {"¥", "prefix"},
+	"eur": {"€", "suffix"},
+}
+
+// resolveCurrencyDisplay determines the symbol and position the "money"
+// template helper uses, falling back to a currency-code-derived default
+// when features.currency_symbol/currency_symbol_position are unset.
+func resolveCurrencyDisplay(cfg *config.Config) (symbol, position string) {
+	symbol = cfg.Features.CurrencySymbol
+	position = cfg.Features.CurrencySymbolPosition
+
+	if symbol != "" && position != "" {
+		return symbol, position
+	}
+
+	def, ok := currencyDisplayDefaults[strings.ToLower(cfg.Coingecko.VsCurrency)]
+	if !ok {
+		def.symbol = strings.ToUpper(cfg.Coingecko.VsCurrency) + " "
+		def.position = "prefix"
+	}
+	if symbol == "" {
+		symbol = def.symbol
+	}
+	if position == "" {
+		position = def.position
+	}
+	return symbol, position
+}
+
+// defaultPageSizeFallback and maxPageSizeFallback are used when
+// features.default_page_size/features.max_page_size are unset, mirroring
+// the zero-falls-back-to-a-default convention used by the other Features
+// knobs (e.g. NotificationMaxTitle).
+const (
+	defaultPageSizeFallback = 25
+	maxPageSizeFallback     = 100
+)
+
+// resolvePageSize parses a page_size query parameter against cfg's
+// features.default_page_size/features.max_page_size, defaulting when raw is
+// empty or doesn't parse as a positive integer, and clamping to the
+// configured max otherwise - so a client can't force an oversized response
+// with something like ?page_size=100000. Shared by any endpoint that grows
+// pagination, so the bound is enforced consistently regardless of which
+// handler adds it first.
+func resolvePageSize(cfg *config.Config, raw string) int {
+	def := cfg.Features.DefaultPageSize
+	if def <= 0 {
+		def = defaultPageSizeFallback
+	}
+	max := cfg.Features.MaxPageSize
+	if max <= 0 {
+		max = maxPageSizeFallback
+	}
+
+	if raw == "" {
+		return def
+	}
+	pageSize, err := strconv.Atoi(raw)
+	if err != nil || pageSize <= 0 {
+		return def
+	}
+	if pageSize > max {
+		return max
+	}
+	return pageSize
+}
+
+// formatAmount renders amount to two decimal places using the given
+// thousands and decimal separators, e.g. (",", ".") for "1,234.56" or
+// (".", ",") for "1.234,56".
+func formatAmount(amount float64, thousands, decimal string) string {
+	negative := amount < 0
+	if negative {
+		amount = -amount
+	}
+
+	cents := int64(math.Round(amount * 100))
+	whole := strconv.FormatInt(cents/100, 10)
+	frac := cents % 100
+
+	var groups []string
+	for len(whole) > 3 {
+		groups = append([]string{whole[len(whole)-3:]}, groups...)
+		whole = whole[:len(whole)-3]
+	}
+	groups = append([]string{whole}, groups...)
+
+	result := fmt.Sprintf("%s%s%02d", strings.Join(groups, thousands), decimal, frac)
+	if negative {
+		result = "-" + result
+	}
+	return result
+}
+
+// moneyFuncMap builds the "money" template helper for a server instance,
+// bound to its resolved currency symbol and position.
+func moneyFuncMap(cfg *config.Config) template.FuncMap {
+	symbol, position := resolveCurrencyDisplay(cfg)
+
+	thousands, decimal := ",", "."
+	if position == "suffix" {
+		thousands, decimal = ".", ","
+	}
+
+	return template.FuncMap{
+		"money": func(amount float64) string {
+			formatted := formatAmount(amount, thousands, decimal)
+			if position == "suffix" {
+				return formatted + " " + symbol
+			}
+			return symbol + formatted
+		},
+	}
 }
 
 // New creates a new server instance
 func New(cfg *config.Config) (*Server, error) {
-	tmpl, err := template.New("").Funcs(funcMap).ParseFS(templatesFS, "templates/*.html", "templates/partials/*.html")
-	if err != nil {
-		return nil, err
+	// features.api_only deployments never render a page, so parsing the
+	// dashboard's templates would just be wasted startup time.
+	var tmpl *template.Template
+	if !cfg.Features.APIOnly {
+		var err error
+		tmpl, err = template.New("").Funcs(baseFuncMap(cfg)).Funcs(moneyFuncMap(cfg)).ParseFS(templatesFS, "templates/*.html", "templates/partials/*.html")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	coinService := coingecko.NewService(cfg.Coins)
+	if cfg.Coingecko.CatalogCachePath != "" {
+		coinService.SetCatalogCachePath(cfg.Coingecko.CatalogCachePath)
+	}
+	if cfg.Coingecko.VsCurrency != "" {
+		coinService.SetVsCurrency(cfg.Coingecko.VsCurrency)
+	}
+	coinService.SetCacheTTL(time.Duration(cfg.Features.PriceCacheTTLMs) * time.Millisecond)
+	coinService.SetRetryConfig(cfg.Features.RetryAttempts, time.Duration(cfg.Features.RetryBaseDelayMs)*time.Millisecond)
+	coinService.SetHistoryCacheSize(cfg.Features.MarketChartCacheSize)
+
+	notificationStore := notifications.NewStore()
+	notificationStore.SetMaxLengths(cfg.Features.NotificationMaxTitle, cfg.Features.NotificationMaxMessage)
+	notificationStore.SetMaxSize(cfg.Features.MaxNotifications)
+
+	if cfg.Features.AvgRefreshIntervalMs == 0 {
+		slog.Warn("avg_refresh_interval_ms_zero", "floor_ms", minRefreshIntervalMs)
+	}
+
+	rateLimit := cfg.Security.RateLimit
+	rateLimiter := middleware.NewRateLimiter(clockwork.NewRealClock(), rateLimit.Enabled, rateLimit.RequestsPerSecond, rateLimit.Burst)
+	var authRateLimiter *middleware.RateLimiter
+	if rateLimit.Enabled && rateLimit.AuthRequestsPerSecond > 0 {
+		authRateLimiter = middleware.NewRateLimiter(clockwork.NewRealClock(), true, rateLimit.AuthRequestsPerSecond, rateLimit.AuthBurst)
+	}
+
+	coinRefreshMs := make(map[string]int, len(cfg.Coins))
+	alertsDisabled := make(map[string]bool, len(cfg.Coins))
+	pinnedCoins := make(map[string]bool, len(cfg.Coins))
+	for _, coin := range cfg.Coins {
+		if coin.RefreshIntervalMs > 0 {
+			coinRefreshMs[coin.ID] = coin.RefreshIntervalMs
+		}
+		if coin.AlertsDisabled {
+			alertsDisabled[coin.ID] = true
+		}
+		if coin.Pinned {
+			pinnedCoins[coin.ID] = true
+		}
+	}
+
+	var canaryTrusted []*net.IPNet
+	for _, cidr := range cfg.Features.Canary.TrustedCIDRs {
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			slog.Warn("invalid_canary_cidr", "cidr", cidr, "error", err)
+			continue
+		}
+		canaryTrusted = append(canaryTrusted, network)
 	}
 
+	shutdownCtx, cancelShutdown := context.WithCancel(context.Background())
+
 	s := &Server{
-		cfg:           cfg,
-		templates:     tmpl,
-		coinService:   coingecko.NewService(cfg.Coins),
-		notifications: notifications.NewStore(),
-		sessions:      session.NewStore(),
-		mux:           http.NewServeMux(),
-		startTime:     time.Now(),
+		cfg:               cfg,
+		templates:         tmpl,
+		coinService:       coinService,
+		notifications:     notificationStore,
+		audit:             audit.NewStore(),
+		sessions:          session.NewStoreWithSkew(clockwork.NewRealClock(), cfg.Security.Session.MaxPerUser, cfg.Security.Session.OnLimit, time.Duration(cfg.Security.Session.TimeoutMs)*time.Millisecond, cfg.Security.Session.SlidingExpiration, time.Duration(cfg.Security.Session.ClockSkewToleranceMs)*time.Millisecond),
+		mux:               http.NewServeMux(),
+		startTime:         time.Now(),
+		rateLimiter:       rateLimiter,
+		authRateLimiter:   authRateLimiter,
+		coinRefreshMs:     coinRefreshMs,
+		alertsDisabled:    alertsDisabled,
+		pinnedCoins:       pinnedCoins,
+		canaryTrusted:     canaryTrusted,
+		announcements:     announcement.NewStore(),
+		reportIdempotency: idempotency.NewStore(reportIdempotencyWindow),
+		reportJobs:        reportjob.NewStore(reportJobTTL),
+		delayGen:          pmath.NewGenerator(time.Now().UnixNano()),
+		draining:          make(chan struct{}),
+		shutdownCtx:       shutdownCtx,
+		cancelShutdown:    cancelShutdown,
 	}
+	s.delayGen.SetClampFactors(cfg.Features.DelayMinFactor, cfg.Features.DelayMaxFactor)
 
-	s.setupRoutes()
+	if err := s.setupRoutes(); err != nil {
+		return nil, err
+	}
 	return s, nil
 }
 
-// setupRoutes configures all HTTP routes
-func (s *Server) setupRoutes() {
-	assetsSubFS, err := fs.Sub(assetsFS, "assets")
+// overlayFS serves files from override when present, falling back to
+// fallback otherwise. override may be nil, in which case fallback is used
+// unconditionally.
+type overlayFS struct {
+	override fs.FS
+	fallback fs.FS
+}
+
+func (o overlayFS) Open(name string) (fs.File, error) {
+	if o.override != nil {
+		if f, err := o.override.Open(name); err == nil {
+			return f, nil
+		}
+	}
+	return o.fallback.Open(name)
+}
+
+// mountAssets serves assets under prefix+"/assets/" from the "assets"
+// directory of the given embedded filesystem. It returns an error if that
+// directory can't be found, which indicates a build problem rather than
+// something recoverable at runtime.
+//
+// When assetsDir is set, files under it take precedence over the embedded
+// copy, letting operators override CSS/JS without rebuilding; files it
+// doesn't provide still fall back to the embedded FS. os.DirFS rejects
+// lexical ".." path segments, so this can't be used to escape assetsDir.
+func mountAssets(mux *http.ServeMux, assets embed.FS, prefix string, assetsDir string) error {
+	info, err := fs.Stat(assets, "assets")
 	if err != nil {
-		slog.Error("failed to create assets sub-filesystem", "error", err)
-	} else {
-		s.mux.Handle("/assets/", http.StripPrefix("/assets/", http.FileServer(http.FS(assetsSubFS))))
+		return fmt.Errorf("assets sub-filesystem not found: %w", err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("assets sub-filesystem not found: %q is not a directory", "assets")
+	}
+
+	assetsSubFS, err := fs.Sub(assets, "assets")
+	if err != nil {
+		return fmt.Errorf("failed to create assets sub-filesystem: %w", err)
+	}
+
+	var servingFS fs.FS = assetsSubFS
+	if assetsDir != "" {
+		dirInfo, err := os.Stat(assetsDir)
+		if err != nil {
+			return fmt.Errorf("server.assets_dir not found: %w", err)
+		}
+		if !dirInfo.IsDir() {
+			return fmt.Errorf("server.assets_dir not found: %q is not a directory", assetsDir)
+		}
+		servingFS = overlayFS{override: os.DirFS(assetsDir), fallback: assetsSubFS}
+	}
+
+	assetsPath := prefix + "/assets/"
+	mux.Handle(assetsPath, http.StripPrefix(assetsPath, http.FileServer(http.FS(servingFS))))
+	return nil
+}
+
+// route prefixes path with server.base_path, so routes and redirects keep
+// working when the app is hosted behind a reverse proxy under a subpath.
+func (s *Server) route(path string) string {
+	return withBasePath(s.config().Server.BasePath, path)
+}
+
+// setupRoutes configures all HTTP routes
+func (s *Server) setupRoutes() error {
+	apiOnly := s.config().Features.APIOnly
+
+	if !apiOnly {
+		if err := mountAssets(s.mux, assetsFS, s.config().Server.BasePath, s.config().Server.AssetsDir); err != nil {
+			return err
+		}
+		s.routes = append(s.routes, s.route("/assets/"))
+
+		// Auth endpoints (no auth required)
+		s.registerRoute(s.route("/login"), s.handleLogin)
+		s.registerRoute(s.route("/auth"), s.handleAuth)
+		s.registerRoute(s.route("/logout"), s.handleLogout)
+
+		// Pages
+		// "/{$}" matches only the exact root, so anything else falls through to
+		// the catch-all "/" registered below rather than into handleIndex.
+		s.registerRoute(s.route("/{$}"), s.handleIndex)
+		s.registerRoute(s.route("/"), s.handleNotFound)
+
+		// HTMX endpoints
+		s.registerRoute(s.route("/ticker"), s.handleTicker)
+		s.registerRoute(s.route("/ticker/"), s.handleTickerCoin) // Per-coin endpoint: /ticker/{coinId}
+		s.registerRoute(s.route("/ticker/changes"), s.handleTickerChanges)
+		s.registerRoute(s.route("/ticker/stream"), s.handleTickerStream)
+		s.registerRoute(s.route("/search"), s.handleSearch)
+		s.registerRoute(s.route("/generate-report"), s.handleGenerateReport)
+		s.registerRoute(s.route("/generate-report/"), s.handleReportJobStatus) // Per-job endpoint: GET /generate-report/{jobID}
+		s.registerRoute(s.route("/notifications"), s.handleNotifications)
+		s.registerRoute(s.route("/notifications/read"), s.handleMarkNotificationsRead)
+		s.registerRoute(s.route("/notifications/"), s.handleDeleteNotification) // Per-notification endpoint: DELETE /notifications/{id}
+	}
+
+	// API endpoints - kept even in api_only mode, since that mode exists to
+	// serve exactly these.
+	s.registerRoute(s.route("/api/ticker"), s.handleAPITicker)
+	s.registerRoute(s.route("/api/ticker/"), s.handleAPITickerRefresh) // Per-coin endpoint: POST /api/ticker/{id}/refresh
+	s.registerRoute(s.route("/api/coins"), s.handleAPICoins)
+	s.registerRoute(s.route("/api/coins/"), s.handleAPICoin) // Per-coin endpoint: /api/coins/{id}
+	s.registerRoute(s.route("/metadata"), s.handleMetadata)
+	s.registerRoute(s.route("/health"), s.handleHealth)
+	s.registerRoute(s.route("/api/health"), s.handleAPIHealth)
+	s.registerRoute(s.route("/api/version/check"), s.handleVersionCheck)
+	s.registerRoute(s.route("/api/portfolio"), s.handleAPIPortfolio)
+
+	if !apiOnly {
+		// Admin endpoints
+		s.registerRoute(s.route("/admin/sessions/revoke"), s.handleAdminRevokeSessions)
+		s.registerRoute(s.route("/admin/audit/export"), s.handleAdminAuditExport)
+		s.registerRoute(s.route("/admin/debug-snapshot"), s.handleAdminDebugSnapshot)
+		s.registerRoute(s.route("/admin/announcement"), s.handleAdminAnnouncement)
+		s.registerRoute(s.route("/api/selftest"), s.handleSelftest)
+	}
+
+	if s.config().Features.MetricsEnabled {
+		s.mux.Handle(s.route("/metrics"), middleware.MetricsHandler())
+		s.routes = append(s.routes, s.route("/metrics"))
+	}
+
+	if s.config().Features.PprofEnabled {
+		s.mountPprof()
 	}
 
-	// Auth endpoints (no auth required)
-	s.mux.HandleFunc("/login", s.handleLogin)
-	s.mux.HandleFunc("/auth", s.handleAuth)
-	s.mux.HandleFunc("/logout", s.handleLogout)
+	s.logRoutes()
+
+	return nil
+}
 
-	// Pages
-	s.mux.HandleFunc("/", s.handleIndex)
+// registerRoute registers handler for pattern on the server's mux and
+// records pattern in s.routes, so logRoutes can report the server's full
+// route surface at boot.
+func (s *Server) registerRoute(pattern string, handler http.HandlerFunc) {
+	s.mux.HandleFunc(pattern, handler)
+	s.routes = append(s.routes, pattern)
+}
 
-	// HTMX endpoints
-	s.mux.HandleFunc("/ticker", s.handleTicker)
-	s.mux.HandleFunc("/ticker/", s.handleTickerCoin) // Per-coin endpoint: /ticker/{coinId}
-	s.mux.HandleFunc("/search", s.handleSearch)
-	s.mux.HandleFunc("/generate-report", s.handleGenerateReport)
-	s.mux.HandleFunc("/notifications", s.handleNotifications)
+// logRoutes logs, at info, every route pattern registered on the server's
+// mux and whether it's reachable without a session (per isPublicEndpoint).
+// Run once setupRoutes has finished, so the log reflects exactly what a
+// request can reach - including routes gated behind a feature flag, like
+// /metrics or /debug/pprof/ - documenting the server's actual surface at
+// boot rather than what a reader would guess from the source alone.
+func (s *Server) logRoutes() {
+	for _, pattern := range s.routes {
+		slog.Info("route_registered", "pattern", pattern, "public", s.isPublicEndpoint(pattern))
+	}
+}
 
-	// API endpoints
-	s.mux.HandleFunc("/metadata", s.handleMetadata)
-	s.mux.HandleFunc("/health", s.handleHealth)
+// mountPprof wires net/http/pprof's handlers onto the server's own mux
+// rather than the default one pprof's init() registers them on
+// (http.DefaultServeMux), so they're picked up by the same auth and logging
+// middleware chain as every other route instead of being reachable
+// unauthenticated on a separate handler. Not listed in isPublicEndpoint, so
+// SessionAuthMiddleware still gates it - but it's still live process
+// introspection, and profiles can surface in-flight request data, so only
+// enable features.pprof_enabled on a deployment you trust the operators of.
+func (s *Server) mountPprof() {
+	prefix := s.route("/debug/pprof")
+	s.registerRoute(prefix+"/", pprof.Index)
+	s.registerRoute(prefix+"/cmdline", pprof.Cmdline)
+	s.registerRoute(prefix+"/profile", pprof.Profile)
+	s.registerRoute(prefix+"/symbol", pprof.Symbol)
+	s.registerRoute(prefix+"/trace", pprof.Trace)
 }
 
 // Handler returns the HTTP handler with middleware applied
 func (s *Server) Handler() http.Handler {
 	// Chain middleware from outermost to innermost:
 	// 1. Recovery - recovers from panics and returns 500
-	// 2. RequestID - adds unique ID to every request
-	// 3. Logging - logs all requests with timing
-	// 4. IPAllowlist - restricts by IP (if enabled)
-	// 5. SessionAuth - requires authentication via session or Basic Auth (if enabled)
-	// 6. mux - actual route handling
+	// 2. HTTPSRedirect - redirects plain-HTTP requests to HTTPS (if enabled)
+	// 3. RequestID - adds unique ID to every request
+	// 4. CSPNonce - adds a per-request nonce for inline scripts
+	// 5. SecurityHeaders - sets X-Content-Type-Options, X-Frame-Options, CSP, HSTS (if enabled)
+	// 6. Metrics - records per-route request counts and latency (if enabled)
+	// 7. Logging - logs all requests with timing
+	// 8. IPAllowlist - restricts by IP (if enabled)
+	// 9. CORS - sets cross-origin headers for JSON API paths (if allowed_origins is set)
+	// 10. RateLimit - throttles by client IP, /auth stricter (if enabled)
+	// 11. SessionAuth - requires authentication via session or Basic Auth (if enabled)
+	// 12. Compression - gzips responses above a size threshold (if enabled)
+	// 13. DebugBodyLogging - logs request/response bodies for allowlisted paths (if logging.level=debug)
+	// 14. ErrorResponse - formats handler-set error statuses by path (JSON vs themed HTML)
+	// 15. mux - actual route handling
 	var handler http.Handler = s.mux
 
+	// Apply error response formatting (innermost - needs the raw status a
+	// route handler set, before Compression touches the body)
+	handler = s.errorResponseMiddleware(handler)
+
+	// Apply debug body logging (innermost, before Compression, so it reads
+	// and logs the handler's actual uncompressed output)
+	handler = middleware.DebugBodyLoggingMiddleware(s.config().Logging.Level, s.config().Logging.DebugBodyPaths)(handler)
+
+	// Apply Compression (innermost - needs the handler's actual output)
+	handler = middleware.CompressionMiddleware(&s.config().Server.Compression)(handler)
+
 	// Apply SessionAuth (innermost security layer)
 	handler = s.sessionAuthMiddleware(handler)
 
-	// Apply IP Allowlist (checked before auth)
-	handler = middleware.IPAllowlistMiddleware(&s.cfg.Security.IPAllowlist)(handler)
+	// Apply RateLimit (before auth, so throttled clients don't even reach it)
+	handler = middleware.RateLimitMiddleware(s.rateLimiter, s.authRateLimiter, s.route("/auth"), s.config().Security.TrustedProxies, s.config().Security.TrustForwardedChain)(handler)
+
+	// Apply CORS (before RateLimit/SessionAuth, so a cross-origin preflight
+	// OPTIONS request doesn't need a session cookie or count against a
+	// client's rate limit). Only isJSONErrorPath paths get CORS headers, so
+	// this can't be used to read an HTML page's session-authenticated
+	// content cross-origin.
+	handler = middleware.CORSMiddleware(s.config().Security.CORS.AllowedOrigins, s.isJSONErrorPath)(handler)
+
+	// Apply IP Allowlist (checked before auth). writeFormattedError gives
+	// blocked HTML clients the themed error page instead of plaintext, while
+	// isJSONErrorPath paths still get the JSON envelope.
+	handler = middleware.IPAllowlistMiddleware(&s.config().Security.IPAllowlist, s.config().Security.TrustedProxies, s.config().Security.TrustForwardedChain, s.writeFormattedError)(handler)
 
 	// Apply logging
-	handler = middleware.LoggingMiddleware(handler)
+	handler = middleware.LoggingMiddleware(s.config().Logging.SlowRequestMs, s.config().Logging.SampleRate, s.config().Logging.SampledPaths, s.config().Security.TrustedProxies, s.config().Security.TrustForwardedChain)(handler)
+
+	// Apply Metrics (outside logging, so a 4xx/5xx from IPAllowlist/SessionAuth
+	// is also counted)
+	handler = middleware.MetricsMiddleware(s.config().Features.MetricsEnabled)(handler)
+
+	// Apply SecurityHeaders (after CSPNonce, so the nonce is in context to
+	// substitute into the Content-Security-Policy it sets)
+	handler = middleware.SecurityHeadersMiddleware(s.config().Security.SecurityHeaders.Enabled, s.config().Security.SecurityHeaders.ContentSecurityPolicy, s.config().Security.TrustedProxies)(handler)
+
+	// Apply CSPNonce (after RequestID, so its own errors still carry a request ID)
+	handler = middleware.CSPNonceMiddleware(handler)
 
 	// Apply RequestID
 	handler = middleware.RequestIDMiddleware(handler)
 
+	// Apply HTTPSRedirect (before RequestID/Logging see a request that's about to be redirected away)
+	handler = middleware.HTTPSRedirectMiddleware(s.config().Security.ForceHTTPS, s.config().Security.TrustedProxies)(handler)
+
 	// Apply Recovery (outermost - catches any panics in the chain)
 	handler = middleware.RecoveryMiddleware(handler)
 
@@ -140,10 +605,25 @@ func (s *Server) Handler() http.Handler {
 // Close gracefully shuts down the server's background resources.
 // This should be called during application shutdown to stop background goroutines.
 func (s *Server) Close() error {
+	s.cancelShutdown()
 	s.sessions.Close()
+	s.rateLimiter.Close()
+	if s.authRateLimiter != nil {
+		s.authRateLimiter.Close()
+	}
 	return nil
 }
 
+// Drain tells every open SSE stream (handleTickerStream) to send a final
+// "reconnect" event and close, rather than leaving clients to notice the
+// connection died on its own. Call this before http.Server.Shutdown during
+// a graceful shutdown, so streams hand off to a healthy instance behind the
+// load balancer instead of holding the shutdown up until it times out.
+// Safe to call more than once.
+func (s *Server) Drain() {
+	s.drainOnce.Do(func() { close(s.draining) })
+}
+
 // PageData holds common data for page rendering
 type PageData struct {
 	Title             string
@@ -154,11 +634,53 @@ type PageData struct {
 	CommitDate        string
 	RequestFeatureURL string
 	ReportBugURL      string
+	// BasePath is exposed to client-side JS (via window.BASE_PATH) for the
+	// handful of fetch() calls that can't use the "url" template function.
+	BasePath string
+	// CSRFToken is echoed into hx-headers on <body> so every HTMX POST
+	// (e.g. /generate-report, /logout) carries it automatically.
+	CSRFToken string
+	// CSPNonce is applied to layout.html's inline <script> tags so they run
+	// under the strict script-src 'nonce-...' CSP that SecurityHeadersMiddleware
+	// sets from the nonce CSPNonceMiddleware generates.
+	CSPNonce string
+	// Announcement is the current operator-set banner, or nil if none is
+	// active (unset or expired).
+	Announcement *announcement.Announcement
+	// Portfolio summarizes configured coin holdings at current prices, for
+	// the dashboard's portfolio summary line. Total is 0 with no Holdings
+	// when no coin has holdings configured.
+	Portfolio coingecko.PortfolioValue
+	// RequestID ties this page render to its server-side logs, so a user
+	// reporting a bug can copy it from a <meta> tag or the footer.
+	RequestID string
+}
+
+// LoginPageData holds data for the login page template.
+type LoginPageData struct {
+	// BasePath is exposed to client-side JS (via window.BASE_PATH) for the
+	// page's fetch('/auth') call.
+	BasePath string
+	// CSRFToken is submitted alongside the login form's fetch('/auth') call.
+	CSRFToken string
 }
 
 // TickerData holds data for the full ticker table (initial load)
 type TickerData struct {
 	Coins []CoinRowData
+	// RefreshMode is the effective features.refresh_mode value ("per_coin",
+	// "full_table", or "off"), which ticker.html uses to decide whether the
+	// table itself polls /ticker on a timer.
+	RefreshMode string
+	// FullTablePollInterval is the htmx hx-trigger polling interval used in
+	// "full_table" mode, e.g. "30s". Derived from
+	// features.avg_refresh_interval_ms so full-table polling tracks the same
+	// knob per-coin polling already uses.
+	FullTablePollInterval string
+	// Degraded is true when the coingecko service is currently rate limited
+	// and serving cached/fallback prices instead of live ones, so
+	// ticker.html can show a banner explaining the numbers may be stale.
+	Degraded bool
 }
 
 // CoinRowData holds data for a single coin row with its delay queue
@@ -168,6 +690,14 @@ type CoinRowData struct {
 	Price       float64
 	Change24h   float64
 	Delays      []int // Queue of 10 delays in milliseconds
+	// RefreshMode is the effective features.refresh_mode value, which
+	// ticker_row.html uses to decide whether this row schedules its own
+	// /ticker/{id} poll.
+	RefreshMode string
+	// Pinned mirrors coins[].pinned for this coin, so ticker_row.html can
+	// style pinned rows (e.g. a pin icon) regardless of where in the table
+	// they landed.
+	Pinned bool
 }
 
 // ReportData holds data for report success template
@@ -176,207 +706,1513 @@ type ReportData struct {
 	NotificationCount int
 }
 
+// ReportPendingData holds data for the report-pending fragment, which polls
+// GET /generate-report/{jobID} until the job completes.
+type ReportPendingData struct {
+	JobID string
+}
+
 // NotificationsData holds data for notifications modal
 type NotificationsData struct {
+	// Notifications is what's actually rendered - the full list, or the
+	// newest features.notifications_display_limit entries when the request
+	// didn't ask to see everything.
 	Notifications []notifications.Notification
-	Count         int
+	// Count is the store's total notification count, which may exceed
+	// len(Notifications) when the display limit truncated the list.
+	Count int
+	// Limited is true when Notifications was truncated to the display
+	// limit, so the template can offer a "view all" link.
+	Limited bool
+	// UnreadCount is how many notifications haven't been marked read, so the
+	// template can offer a "mark all read" action only when it'd do something.
+	UnreadCount int
+}
+
+// NotificationBadgeData holds data for the notification bell's unread-count
+// badge, rendered both as part of the full page and as its own fragment.
+type NotificationBadgeData struct {
+	NotificationCount int
+}
+
+// renderTemplate executes the named template into an in-memory buffer and
+// only copies it to w on success. ExecuteTemplate writes directly to its
+// io.Writer, so a mid-render failure (e.g. a range erroring halfway through)
+// would otherwise leave the client with a half-written page followed by a
+// 500 that can no longer change the already-sent status or body.
+func (s *Server) renderTemplate(w http.ResponseWriter, r *http.Request, name string, data interface{}) error {
+	var buf bytes.Buffer
+	if err := s.templates.ExecuteTemplate(&buf, name, data); err != nil {
+		middleware.Logger(r.Context()).Error("template_error", "template", name, "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return err
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_, err := buf.WriteTo(w)
+	return err
+}
+
+// refreshIntervalForCoin returns coinID's mean refresh interval, honoring
+// coins[].refresh_interval_ms when the coin sets one and falling back to
+// features.avg_refresh_interval_ms otherwise.
+func (s *Server) refreshIntervalForCoin(coinID string) int {
+	if ms, ok := s.coinRefreshMs[coinID]; ok && ms > 0 {
+		return ms
+	}
+	return s.config().Features.AvgRefreshIntervalMs
+}
+
+// refreshMode returns the effective features.refresh_mode value, defaulting
+// to "per_coin" when unset.
+func (s *Server) refreshMode() string {
+	if s.config().Features.RefreshMode == "" {
+		return "per_coin"
+	}
+	return s.config().Features.RefreshMode
+}
+
+// fullTablePollInterval renders features.avg_refresh_interval_ms as an
+// htmx hx-trigger polling interval (e.g. "30s") for "full_table" refresh
+// mode, matching the value per-coin polling already centers its delays on.
+func (s *Server) fullTablePollInterval() string {
+	ms := s.config().Features.AvgRefreshIntervalMs
+	if ms <= 0 {
+		ms = 5000
+	}
+	seconds := ms / 1000
+	if seconds < 1 {
+		seconds = 1
+	}
+	return fmt.Sprintf("%ds", seconds)
+}
+
+// alertsEnabledForCoin reports whether coinID may trigger price/percent
+// alerts, honoring coins[].alerts_disabled so stablecoins and other
+// deliberately opted-out coins stay quiet. There is no alert evaluation
+// engine yet; this is the gate a future one should consult before creating
+// a notification for a coin.
+func (s *Server) alertsEnabledForCoin(coinID string) bool {
+	return !s.alertsDisabled[coinID]
+}
+
+// featureHeaderPrefix namespaces canary override headers, e.g.
+// "X-Feature-NewTicker: on" overrides the "NewTicker" flag.
+const featureHeaderPrefix = "X-Feature-"
+
+// FeatureEnabled reports whether the named feature flag is enabled for r.
+// It starts from features.canary.flags[name] (disabled if the name isn't
+// present) and lets a trusted caller override it per-request via an
+// "X-Feature-<name>: on"/"off" header, so an in-development behavior can be
+// canary-tested against production traffic without a global config flip.
+// The header is only honored from a request isCanaryTrusted allows; anyone
+// else's header is ignored and the configured default applies.
+func (s *Server) FeatureEnabled(r *http.Request, name string) bool {
+	enabled := s.config().Features.Canary.Flags[name]
+
+	header := r.Header.Get(featureHeaderPrefix + name)
+	if header == "" || !s.isCanaryTrusted(r) {
+		return enabled
+	}
+
+	switch strings.ToLower(header) {
+	case "on", "true", "1":
+		return true
+	case "off", "false", "0":
+		return false
+	default:
+		return enabled
+	}
+}
+
+// isCanaryTrusted reports whether r may override a feature flag via header:
+// either it carries a valid session (an authenticated operator/tester), or
+// its address falls within features.canary.trusted_cidrs (e.g. an internal
+// canary load generator that never logs in).
+func (s *Server) isCanaryTrusted(r *http.Request) bool {
+	if s.getSessionFromRequest(r) != nil {
+		return true
+	}
+	if len(s.canaryTrusted) == 0 {
+		return false
+	}
+
+	host := r.RemoteAddr
+	if h, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		host = h
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return false
+	}
+	for _, network := range s.canaryTrusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// SetConfigSource records where the running config was loaded from (e.g.
+// the config file path viper resolved), for inclusion in DebugSnapshot.
+// It's a setter rather than a New() parameter because config source
+// resolution lives in cmd/coinops, which imports this package - New()
+// can't depend on it without an import cycle. Left empty, DebugSnapshot
+// just reports an empty config source.
+func (s *Server) SetConfigSource(source string) {
+	s.configSource = source
+}
+
+// ValidateVsCurrency reports whether coingecko.vs_currency is one CoinGecko
+// actually supports. Intended to be called once at startup, before serve
+// starts accepting requests, so a typo shows up as a clear startup error or
+// warning instead of a ticker full of silent zeros.
+func (s *Server) ValidateVsCurrency(ctx context.Context) error {
+	return s.coinService.ValidateVsCurrency(ctx)
+}
+
+// config returns the currently active configuration. Handlers and
+// background loops should call this instead of reading the cfg field
+// directly, so a concurrent ReloadConfig can't be observed mid-swap.
+func (s *Server) config() *config.Config {
+	s.cfgMu.RLock()
+	defer s.cfgMu.RUnlock()
+	return s.cfg
+}
+
+// ReloadConfig swaps the server's live config for newCfg - e.g. in response
+// to a SIGHUP (see cmd/coinops/serve.go) - and tells coinService about the
+// new coin list so an added/removed coin takes effect without a restart.
+// Callers are expected to have already validated newCfg (cmd/coinops's
+// config.Validate()); this only performs the swap itself.
+//
+// Not everything picks up a reload: the per-request middleware chain built
+// once in Handler() closes over config values like rate limits, CORS
+// origins, the IP allowlist, security headers and HTTPS-redirect at
+// startup, so those still need a restart. Values read live via config()
+// on each request or delay-queue generation - AvgRefreshIntervalMs, the
+// coin list, feature flags, display settings - take effect immediately.
+//
+// It returns the names of the top-level config sections that changed, for
+// logging a diff summary alongside config_reloaded rather than dumping the
+// whole (credential-adjacent) config.
+func (s *Server) ReloadConfig(newCfg *config.Config) []string {
+	s.cfgMu.Lock()
+	oldCfg := s.cfg
+	s.cfg = newCfg
+	s.cfgMu.Unlock()
+
+	s.coinService.SetCoins(newCfg.Coins)
+
+	return diffConfigSummary(oldCfg, newCfg)
+}
+
+// diffConfigSummary reports which top-level Config fields differ between
+// old and new.
+func diffConfigSummary(oldCfg, newCfg *config.Config) []string {
+	var changed []string
+
+	oldVal := reflect.ValueOf(*oldCfg)
+	newVal := reflect.ValueOf(*newCfg)
+	t := oldVal.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if !reflect.DeepEqual(oldVal.Field(i).Interface(), newVal.Field(i).Interface()) {
+			changed = append(changed, t.Field(i).Name)
+		}
+	}
+
+	return changed
+}
+
+// minRefreshIntervalMs is the floor generateDelayQueue substitutes for a
+// zero (misconfigured or unset) refresh interval. GetPoissonDelay(0) always
+// returns 0, which would have clients poll in a tight loop and hammer the
+// server, so a zero mean is treated as a configuration mistake rather than
+// "refresh as fast as possible".
+const minRefreshIntervalMs = 1000
+
+// generateDelayQueue creates a queue of 10 randomized delays centered on
+// coinID's mean refresh interval, drawn from the server's own delayGen so
+// tests can seed it for a deterministic queue. features.delay_distribution
+// picks which distribution: "poisson" draws a discrete event count via
+// Generator.Count, anything else (including unset) uses Generator.Delay's
+// exponential inter-arrival draw.
+func (s *Server) generateDelayQueue(coinID string) []int {
+	mean := s.refreshIntervalForCoin(coinID)
+	if mean <= 0 {
+		mean = minRefreshIntervalMs
+	}
+	delays := make([]int, 10)
+	s.delayGenMu.Lock()
+	defer s.delayGenMu.Unlock()
+	for i := range delays {
+		if s.config().Features.DelayDistribution == "poisson" {
+			delays[i] = s.delayGen.Count(float64(mean))
+		} else {
+			delays[i] = s.delayGen.Delay(float64(mean))
+		}
+	}
+	return delays
+}
+
+// handleIndex renders the main dashboard page. It's registered against the
+// exact-root "/{$}" pattern, so genuinely unrouted paths reach
+// handleNotFound instead.
+func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
+	csrfToken, err := s.csrfToken(w, r)
+	if err != nil {
+		middleware.Logger(r.Context()).Error("csrf_token_error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	versionInfo := version.Get()
+	data := PageData{
+		Title:             "Dashboard",
+		NotificationCount: s.notifications.UnreadCount(),
+		AvgRefreshMs:      s.config().Features.AvgRefreshIntervalMs,
+		Version:           versionInfo.Version,
+		Commit:            versionInfo.Commit,
+		CommitDate:        versionInfo.CommitDate,
+		RequestFeatureURL: s.config().Links.RequestFeatureURL,
+		ReportBugURL:      s.config().Links.ReportBugURL,
+		BasePath:          s.config().Server.BasePath,
+		CSRFToken:         csrfToken,
+		CSPNonce:          middleware.GetCSPNonce(r.Context()),
+		Announcement:      s.announcements.Get(),
+		Portfolio:         s.coinService.PortfolioValue(),
+		RequestID:         middleware.GetRequestID(r.Context()),
+	}
+
+	s.renderTemplate(w, r, "layout.html", data)
+}
+
+// handleNotFound is the catch-all fallback for any path not matched by a
+// more specific route, including "/" once the exact-root "/{$}" pattern has
+// claimed handleIndex's spot. It shares the same themed error.html/JSON
+// content negotiation as every other error response instead of a bare
+// http.NotFound, via writeFormattedError.
+func (s *Server) handleNotFound(w http.ResponseWriter, r *http.Request) {
+	s.writeFormattedError(w, r, http.StatusNotFound, "Page not found")
+}
+
+// ErrorResponse is the JSON error envelope written by writeError for
+// HTMX/JSON clients.
+type ErrorResponse struct {
+	Error     string `json:"error"`
+	RequestID string `json:"request_id"`
+}
+
+// writeError reports a handler failure to the client. HTMX requests (marked
+// with the HX-Request header) and clients that ask for JSON via Accept get a
+// structured ErrorResponse envelope carrying the request ID, so front-end
+// code can build error toasts without scraping plaintext. Everyone else gets
+// the plain http.Error body they'd get from any other Go server.
+func (s *Server) writeError(w http.ResponseWriter, r *http.Request, message string, status int) {
+	if r.Header.Get("HX-Request") == "true" || strings.Contains(r.Header.Get("Accept"), "application/json") {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(ErrorResponse{
+			Error:     message,
+			RequestID: middleware.GetRequestID(r.Context()),
+		}); err != nil {
+			middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", r.URL.Path, "error", err)
+		}
+		return
+	}
+	http.Error(w, message, status)
+}
+
+// writeServiceUnavailable reports that s.coinService currently has no
+// prices to serve (upstream fetch failed and there was no cached/fallback
+// data to fall back to). It sets Retry-After from the service's rate-limit
+// cooldown, defaulting to defaultRetryAfterSeconds when the service isn't
+// reporting one, so well-behaved clients back off instead of retrying
+// immediately.
+const defaultRetryAfterSeconds = 30
+
+func (s *Server) writeServiceUnavailable(w http.ResponseWriter, r *http.Request, message string) {
+	retryAfter := defaultRetryAfterSeconds
+	if d := s.coinService.RateLimitRetryAfter(); d > 0 {
+		retryAfter = int(math.Ceil(d.Seconds()))
+	}
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	s.writeError(w, r, message, http.StatusServiceUnavailable)
+}
+
+// jsonErrorPathPrefixes lists route prefixes whose error responses are
+// always JSON, regardless of what an individual handler wrote, so
+// programmatic clients (alerting scripts, monitoring probes) get a
+// consistent contract instead of per-endpoint content negotiation.
+var jsonErrorPathPrefixes = []string{"/api/", "/metadata", "/health"}
+
+// isJSONErrorPath reports whether path should render its error responses as
+// JSON rather than the themed HTML error page.
+func (s *Server) isJSONErrorPath(path string) bool {
+	for _, prefix := range jsonErrorPathPrefixes {
+		if strings.HasPrefix(path, s.route(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrorPageData holds data for the themed error.html template.
+type ErrorPageData struct {
+	StatusCode int
+	StatusText string
+	Message    string
+	BasePath   string
+}
+
+// renderErrorPage renders the themed error.html template for HTML clients.
+// A template execution failure falls back to a plain http.Error rather than
+// risking a second panic inside error handling.
+func (s *Server) renderErrorPage(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if s.templates == nil {
+		// features.api_only never parses templates - every path falls under
+		// isJSONErrorPath in that mode, but fall back safely here too rather
+		// than assume that stays true forever.
+		http.Error(w, message, status)
+		return
+	}
+
+	var buf bytes.Buffer
+	data := ErrorPageData{
+		StatusCode: status,
+		StatusText: http.StatusText(status),
+		Message:    message,
+		BasePath:   s.config().Server.BasePath,
+	}
+	if err := s.templates.ExecuteTemplate(&buf, "error.html", data); err != nil {
+		middleware.Logger(r.Context()).Error("template_error", "template", "error.html", "error", err)
+		http.Error(w, message, status)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	buf.WriteTo(w)
+}
+
+// writeFormattedError renders status/message in the format appropriate for
+// r's path: JSON for isJSONErrorPath paths, the themed error.html page
+// otherwise. It's used by errorResponseMiddleware to backfill a consistent
+// response for handlers that only called http.Error/w.WriteHeader without
+// formatting their own body (e.g. http.NotFound, method-not-allowed guards).
+func (s *Server) writeFormattedError(w http.ResponseWriter, r *http.Request, status int, message string) {
+	if message == "" {
+		message = http.StatusText(status)
+	}
+
+	if s.isJSONErrorPath(r.URL.Path) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		if err := json.NewEncoder(w).Encode(ErrorResponse{
+			Error:     message,
+			RequestID: middleware.GetRequestID(r.Context()),
+		}); err != nil {
+			middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", r.URL.Path, "error", err)
+		}
+		return
+	}
+
+	s.renderErrorPage(w, r, status, message)
+}
+
+// errorResponseWriter buffers a handler's response so errorResponseMiddleware
+// can inspect the final status code before anything reaches the client.
+type errorResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func newErrorResponseWriter(w http.ResponseWriter) *errorResponseWriter {
+	return &errorResponseWriter{ResponseWriter: w, status: http.StatusOK}
+}
+
+func (w *errorResponseWriter) WriteHeader(status int) {
+	w.status = status
+}
+
+func (w *errorResponseWriter) Write(b []byte) (int, error) {
+	return w.body.Write(b)
+}
+
+// errorResponseMiddleware gives every handler-set error status a
+// consistent, path-based response format instead of per-handler content
+// negotiation: JSON for isJSONErrorPath paths, a themed HTML error page for
+// everything else. A handler that already wrote its own
+// "application/json" error body (e.g. writeError negotiating on
+// Accept/HX-Request) is passed through unchanged.
+func (s *Server) errorResponseMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if middleware.IsEventStreamRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		erw := newErrorResponseWriter(w)
+		next.ServeHTTP(erw, r)
+
+		if erw.status < http.StatusBadRequest || w.Header().Get("Content-Type") == "application/json" {
+			w.WriteHeader(erw.status)
+			w.Write(erw.body.Bytes())
+			return
+		}
+
+		s.writeFormattedError(w, r, erw.status, strings.TrimSpace(erw.body.String()))
+	})
 }
 
-// generateDelayQueue creates a queue of 10 Poisson-distributed delays
-func (s *Server) generateDelayQueue() []int {
-	delays := make([]int, 10)
-	for i := range delays {
-		delays[i] = pmath.GetPoissonDelay(float64(s.cfg.Features.AvgRefreshIntervalMs))
+// handleTicker returns the full crypto price table (initial load)
+func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
+	coins, err := s.coinService.GetPrices()
+	if err != nil {
+		s.writeServiceUnavailable(w, r, "Prices are temporarily unavailable")
+		return
+	}
+
+	mode := s.refreshMode()
+	coinData := make([]CoinRowData, len(coins))
+	for i, c := range coins {
+		coinData[i] = CoinRowData{
+			ID:          c.ID,
+			DisplayName: c.DisplayName,
+			Price:       c.Price,
+			Change24h:   c.Change24h,
+			Delays:      s.generateDelayQueue(c.ID),
+			RefreshMode: mode,
+			Pinned:      s.pinnedCoins[c.ID],
+		}
+	}
+	sortPinnedFirst(coinData)
+
+	data := TickerData{
+		Coins:                 coinData,
+		RefreshMode:           mode,
+		FullTablePollInterval: s.fullTablePollInterval(),
+		Degraded:              s.coinService.IsRateLimited(),
+	}
+
+	s.renderTemplate(w, r, "ticker.html", data)
+}
+
+// sortPinnedFirst stably reorders coins so pinned ones lead, preserving
+// their relative order (and the relative order of the rest) otherwise -
+// so pinning composes with whatever order coins arrived in, sorted or not.
+func sortPinnedFirst(coins []CoinRowData) {
+	sort.SliceStable(coins, func(i, j int) bool {
+		return coins[i].Pinned && !coins[j].Pinned
+	})
+}
+
+// historyDays is how many days of price history handleTickerCoinHistory
+// returns, enough to draw a simple week-long sparkline.
+const historyDays = 7
+
+// handleTickerCoin returns a single coin row (async refresh per coin)
+func (s *Server) handleTickerCoin(w http.ResponseWriter, r *http.Request) {
+	// Extract coin ID from path: /ticker/{coinId}
+	coinID := strings.TrimPrefix(r.URL.Path, s.route("/ticker/"))
+	if coinID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	if base, ok := strings.CutSuffix(coinID, "/history"); ok {
+		s.handleTickerCoinHistory(w, r, base)
+		return
+	}
+
+	coin, err := s.coinService.GetCoin(coinID)
+	if err != nil {
+		s.writeError(w, r, "Coin not found", http.StatusNotFound)
+		return
+	}
+
+	data := CoinRowData{
+		ID:          coin.ID,
+		DisplayName: coin.DisplayName,
+		Price:       coin.Price,
+		Change24h:   coin.Change24h,
+		Delays:      s.generateDelayQueue(coin.ID),
+		RefreshMode: s.refreshMode(),
+		Pinned:      s.pinnedCoins[coin.ID],
+	}
+
+	s.renderTemplate(w, r, "ticker_row.html", data)
+}
+
+// CoinHistoryResponse is the JSON response from handleTickerCoinHistory.
+type CoinHistoryResponse struct {
+	ID     string                 `json:"id"`
+	Points []coingecko.PricePoint `json:"points"`
+}
+
+// handleTickerCoinHistory returns coinID's recent price history as JSON, for
+// rendering a sparkline chart. Path: GET /ticker/{coinId}/history.
+func (s *Server) handleTickerCoinHistory(w http.ResponseWriter, r *http.Request, coinID string) {
+	if coinID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	points, err := s.coinService.GetHistory(coinID, historyDays)
+	if err != nil {
+		s.writeError(w, r, "Coin not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(CoinHistoryResponse{ID: coinID, Points: points}); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/ticker/{id}/history", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// CoinChange is a single coin's current price, used in the JSON responses
+// from handleTickerChanges and handleAPITicker.
+type CoinChange struct {
+	ID          string  `json:"id"`
+	DisplayName string  `json:"display_name"`
+	Price       float64 `json:"price"`
+	Change24h   float64 `json:"change_24h"`
+}
+
+// TickerChangesResponse is the JSON response from handleTickerChanges.
+type TickerChangesResponse struct {
+	Generation int64        `json:"generation"`
+	Changed    []CoinChange `json:"changed"`
+}
+
+// handleTickerChanges returns only the coins whose price has changed since
+// the generation given in ?since=, plus the current generation, so a
+// client can poll for updates without re-fetching every coin on its own
+// schedule. An empty or invalid ?since= is treated as 0, returning every
+// coin's current price.
+func (s *Server) handleTickerChanges(w http.ResponseWriter, r *http.Request) {
+	var since int64
+	if raw := r.URL.Query().Get("since"); raw != "" {
+		if parsed, err := strconv.ParseInt(raw, 10, 64); err == nil {
+			since = parsed
+		}
+	}
+
+	coins, generation, err := s.coinService.Changes(since)
+	if err != nil {
+		s.writeError(w, r, "Failed to fetch prices", http.StatusInternalServerError)
+		return
+	}
+
+	changed := make([]CoinChange, len(coins))
+	for i, c := range coins {
+		changed[i] = CoinChange{
+			ID:          c.ID,
+			DisplayName: c.DisplayName,
+			Price:       c.Price,
+			Change24h:   c.Change24h,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(TickerChangesResponse{Generation: generation, Changed: changed}); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/ticker/changes", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// sseStreamPollInterval controls how often handleTickerStream checks
+// coinService for changes to push. Changes only re-fetches from CoinGecko
+// once the cache is stale, so polling this often costs little more than
+// handleTickerChanges does per HTMX poll cycle.
+const sseStreamPollInterval = 1 * time.Second
+
+// sseHeartbeatInterval is how often handleTickerStream sends a comment-only
+// keepalive frame when nothing has changed, so intermediary proxies and
+// load balancers don't time out an otherwise-idle connection.
+const sseHeartbeatInterval = 15 * time.Second
+
+// handleTickerStream pushes price updates over Server-Sent Events as the
+// coingecko cache refreshes, for clients that want lower latency than the
+// per-coin Poisson-scheduled polling ticker_row.html falls back to. Each
+// update is a "data:" frame carrying JSON shaped like a CoinChange; sitting
+// on top of coinService.Changes means a client only ever receives the
+// coins that actually moved, same as GET /ticker/changes. The polling
+// endpoints (/ticker/{id}, /ticker/changes) stay registered unchanged, so a
+// client without EventSource support keeps working exactly as before. When
+// Drain is called (see Server.Drain), the stream sends a final
+// "event: reconnect" frame and closes, rather than staying open until the
+// underlying connection is torn down.
+func (s *Server) handleTickerStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	ctx := r.Context()
+	poll := time.NewTicker(sseStreamPollInterval)
+	defer poll.Stop()
+	heartbeat := time.NewTicker(sseHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	var since int64
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.draining:
+			fmt.Fprint(w, "event: reconnect\ndata: server draining, please reconnect\n\n")
+			flusher.Flush()
+			return
+		case <-poll.C:
+			coins, generation, err := s.coinService.Changes(since)
+			if err != nil {
+				middleware.Logger(ctx).Error("sse_changes_error", "endpoint", "/ticker/stream", "error", err)
+				continue
+			}
+			since = generation
+			if len(coins) == 0 {
+				continue
+			}
+			for _, c := range coins {
+				payload, err := json.Marshal(CoinChange{
+					ID:          c.ID,
+					DisplayName: c.DisplayName,
+					Price:       c.Price,
+					Change24h:   c.Change24h,
+				})
+				if err != nil {
+					continue
+				}
+				fmt.Fprintf(w, "data: %s\n\n", payload)
+			}
+			flusher.Flush()
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+			flusher.Flush()
+		}
+	}
+}
+
+// APITickerResponse is the JSON response from handleAPITicker.
+type APITickerResponse struct {
+	Coins []CoinChange `json:"coins"`
+}
+
+// handleAPITicker returns prices for just the coins listed in the
+// comma-separated ?ids= query param, for callers (e.g. a custom widget)
+// that only care about a subset and don't want to fetch and filter the
+// full ticker client-side. An empty or missing ?ids= returns an empty
+// list. An unknown ID is skipped with a warning logged, unless
+// features.ticker_strict_unknown_ids is set, in which case it 404s the
+// whole request.
+func (s *Server) handleAPITicker(w http.ResponseWriter, r *http.Request) {
+	idsParam := strings.TrimSpace(r.URL.Query().Get("ids"))
+	if idsParam == "" {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(APITickerResponse{Coins: []CoinChange{}})
+		return
+	}
+
+	coins, err := s.coinService.GetPrices()
+	if err != nil {
+		s.writeServiceUnavailable(w, r, "Prices are temporarily unavailable")
+		return
+	}
+
+	byID := make(map[string]coingecko.Coin, len(coins))
+	for _, c := range coins {
+		byID[c.ID] = c
+	}
+
+	requestedIDs := strings.Split(idsParam, ",")
+	result := make([]CoinChange, 0, len(requestedIDs))
+	for _, id := range requestedIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+
+		coin, ok := byID[id]
+		if !ok {
+			if s.config().Features.TickerStrictUnknownIDs {
+				s.writeError(w, r, fmt.Sprintf("unknown coin id: %s", id), http.StatusNotFound)
+				return
+			}
+			middleware.Logger(r.Context()).Warn("unknown_ticker_id_ignored", "id", id)
+			continue
+		}
+
+		result = append(result, CoinChange{
+			ID:          coin.ID,
+			DisplayName: coin.DisplayName,
+			Price:       coin.Price,
+			Change24h:   coin.Change24h,
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(APITickerResponse{Coins: result}); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/ticker", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleAPICoins returns every configured coin's current price as a plain
+// JSON array, for alerting scripts and other programmatic consumers that
+// don't want to scrape /ticker's HTML.
+func (s *Server) handleAPICoins(w http.ResponseWriter, r *http.Request) {
+	coins, err := s.coinService.GetPrices()
+	if err != nil {
+		s.writeServiceUnavailable(w, r, "Prices are temporarily unavailable")
+		return
+	}
+
+	result := make([]CoinChange, len(coins))
+	for i, c := range coins {
+		result[i] = CoinChange{
+			ID:          c.ID,
+			DisplayName: c.DisplayName,
+			Price:       c.Price,
+			Change24h:   c.Change24h,
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(result); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/coins", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// PortfolioHoldingResponse is a single held coin's contribution to the
+// PortfolioResponse total.
+type PortfolioHoldingResponse struct {
+	ID       string  `json:"id"`
+	Holdings float64 `json:"holdings"`
+	Price    float64 `json:"price"`
+	Value    float64 `json:"value"`
+}
+
+// PortfolioResponse is the /api/portfolio response body.
+type PortfolioResponse struct {
+	Total float64 `json:"total"`
+	// Partial is true when at least one held coin's price couldn't be
+	// determined, meaning Total understates the real portfolio value.
+	Partial  bool                       `json:"partial"`
+	Holdings []PortfolioHoldingResponse `json:"holdings"`
+}
+
+// handleAPIPortfolio returns the total value of every configured coin
+// holding at current prices. Path: GET /api/portfolio.
+func (s *Server) handleAPIPortfolio(w http.ResponseWriter, r *http.Request) {
+	pv := s.coinService.PortfolioValue()
+
+	holdings := make([]PortfolioHoldingResponse, len(pv.Holdings))
+	for i, h := range pv.Holdings {
+		holdings[i] = PortfolioHoldingResponse{
+			ID:       h.ID,
+			Holdings: h.Holdings,
+			Price:    h.Price,
+			Value:    h.Value,
+		}
+	}
+
+	response := PortfolioResponse{
+		Total:    pv.Total,
+		Partial:  pv.Partial,
+		Holdings: holdings,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/portfolio", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleAPICoin returns a single configured coin's current price as JSON.
+// Path: GET /api/coins/{id}.
+func (s *Server) handleAPICoin(w http.ResponseWriter, r *http.Request) {
+	coinID := strings.TrimPrefix(r.URL.Path, s.route("/api/coins/"))
+	if coinID == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	coin, err := s.coinService.GetCoin(coinID)
+	if err != nil {
+		s.writeError(w, r, "Coin not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(CoinChange{
+		ID:          coin.ID,
+		DisplayName: coin.DisplayName,
+		Price:       coin.Price,
+		Change24h:   coin.Change24h,
+	}); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/coins/{id}", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleAPITickerRefresh forces a fresh upstream price fetch for a single
+// tracked coin, so an operator who suspects a stale value can verify it
+// without waiting out the price cache. Path: POST /api/ticker/{id}/refresh.
+func (s *Server) handleAPITickerRefresh(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, s.route("/api/ticker/"))
+	coinID := strings.TrimSuffix(path, "/refresh")
+	if coinID == "" || coinID == path {
+		http.NotFound(w, r)
+		return
+	}
+
+	coin, err := s.coinService.RefreshCoin(coinID)
+	if err != nil {
+		s.writeError(w, r, "Coin not found", http.StatusNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(CoinChange{
+		ID:          coin.ID,
+		DisplayName: coin.DisplayName,
+		Price:       coin.Price,
+		Change24h:   coin.Change24h,
+	}); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/ticker/{id}/refresh", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// handleSearch filters coins by search query
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query().Get("search")
+
+	coins, err := s.coinService.SearchCoins(query)
+	if err != nil {
+		s.writeError(w, r, "Failed to search", http.StatusInternalServerError)
+		return
+	}
+
+	mode := s.refreshMode()
+	coinData := make([]CoinRowData, len(coins))
+	for i, c := range coins {
+		coinData[i] = CoinRowData{
+			ID:          c.ID,
+			DisplayName: c.DisplayName,
+			Price:       c.Price,
+			Change24h:   c.Change24h,
+			Delays:      s.generateDelayQueue(c.ID),
+			RefreshMode: mode,
+			Pinned:      s.pinnedCoins[c.ID],
+		}
+	}
+
+	data := TickerData{
+		Coins:                 coinData,
+		RefreshMode:           mode,
+		FullTablePollInterval: s.fullTablePollInterval(),
+		Degraded:              s.coinService.IsRateLimited(),
+	}
+
+	s.renderTemplate(w, r, "ticker.html", data)
+}
+
+// reportIdempotencyWindow is how long a given Idempotency-Key on
+// /generate-report suppresses a duplicate report, so a flaky client's retry
+// gets back the same job instead of kicking off (and eventually notifying
+// about) a second one.
+const reportIdempotencyWindow = 5 * time.Minute
+
+// reportJobTTL is how long a report job's status stays queryable after
+// being created, which must be at least reportIdempotencyWindow so a
+// cached-by-key retry can still look its job up.
+const reportJobTTL = reportIdempotencyWindow
+
+// defaultReportDurationMs is how long runReportJob simulates work for when
+// features.report_duration_ms is zero or unset.
+const defaultReportDurationMs = 3000
+
+// reportDuration returns the configured simulated report generation time,
+// falling back to defaultReportDurationMs.
+func (s *Server) reportDuration() time.Duration {
+	ms := s.config().Features.ReportDurationMs
+	if ms <= 0 {
+		ms = defaultReportDurationMs
+	}
+	return time.Duration(ms) * time.Millisecond
+}
+
+// handleGenerateReport kicks off report generation in the background and
+// immediately returns 202 with a pending fragment that polls
+// GET /generate-report/{jobID} for the result. This keeps the (deliberately
+// slow) simulated work off the request's connection, which would otherwise
+// risk tripping the server's write timeout.
+func (s *Server) handleGenerateReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.validateCSRFToken(r) {
+		middleware.Logger(r.Context()).Warn("csrf_validation_failed", "path", r.URL.Path, "ip", r.RemoteAddr)
+		http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+		return
+	}
+
+	idempotencyKey := r.Header.Get("Idempotency-Key")
+
+	var jobID string
+	var loaded bool
+	var err error
+	if idempotencyKey != "" {
+		// GetOrSet runs the check-then-create as one atomic step, so two
+		// requests carrying the same Idempotency-Key that race in - the
+		// flaky-client-retries-a-slow-report case this key exists for -
+		// can't both miss the cache and both create a job.
+		jobID, loaded, err = s.reportIdempotency.GetOrSet(idempotencyKey, s.reportJobs.Create)
+	} else {
+		jobID, err = s.reportJobs.Create()
+	}
+	if err != nil {
+		middleware.Logger(r.Context()).Error("report_job_create_error", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	if loaded {
+		w.WriteHeader(http.StatusAccepted)
+		s.writeReportJobStatus(w, r, jobID)
+		return
+	}
+
+	// runReportJob outlives this request (handleGenerateReport returns as
+	// soon as it's launched), so it can't use r.Context() directly - that
+	// context is canceled the moment ServeHTTP returns, which would abort
+	// every job immediately. Instead it gets a context that keeps the
+	// request's logger and other values (via context.WithoutCancel) but is
+	// canceled by s.shutdownCtx, so an in-flight job aborts on graceful
+	// shutdown rather than never.
+	jobCtx, cancelJob := context.WithCancel(context.WithoutCancel(r.Context()))
+	stopOnShutdown := context.AfterFunc(s.shutdownCtx, cancelJob)
+	go func() {
+		defer stopOnShutdown()
+		s.runReportJob(jobCtx, jobID)
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+	s.renderTemplate(w, r, "report-pending.html", ReportPendingData{JobID: jobID})
+}
+
+// runReportJob simulates a slow admin operation and completes jobID once
+// done, unless ctx is canceled first - in which case it returns without
+// adding a notification or completing the job, leaving it to expire via its
+// TTL. It's launched in its own goroutine so handleGenerateReport can return
+// immediately; because of that, ctx only reaches Done() on server shutdown
+// (see the shutdownCtx wiring in handleGenerateReport), not when the client
+// that requested the report disconnects - by the time this goroutine starts,
+// that request has already completed, so there's nothing left to disconnect
+// from.
+func (s *Server) runReportJob(ctx context.Context, jobID string) {
+	select {
+	case <-time.After(s.reportDuration()):
+	case <-ctx.Done():
+		middleware.Logger(ctx).Info("report_canceled", "job_id", jobID)
+		return
+	}
+
+	timestamp := time.Now().Format("20060102_150405")
+	s.notifications.Add("Report Ready", "Compliance report "+timestamp+" generated successfully")
+	s.reportJobs.Complete(jobID, timestamp)
+}
+
+// handleReportJobStatus returns a report job's current status - a pending
+// fragment that keeps polling itself, or the finished report-success.html
+// once done. Path: GET /generate-report/{jobID}.
+func (s *Server) handleReportJobStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	jobID := strings.TrimPrefix(r.URL.Path, s.route("/generate-report/"))
+	s.writeReportJobStatus(w, r, jobID)
+}
+
+// writeReportJobStatus renders jobID's current status, or 404 if it doesn't
+// exist (never created, or its TTL passed). Shared by handleGenerateReport's
+// idempotent-retry path and handleReportJobStatus's poll.
+func (s *Server) writeReportJobStatus(w http.ResponseWriter, r *http.Request, jobID string) {
+	job, ok := s.reportJobs.Get(jobID)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	if job.Status == reportjob.StatusPending {
+		s.renderTemplate(w, r, "report-pending.html", ReportPendingData{JobID: jobID})
+		return
+	}
+
+	s.renderTemplate(w, r, "report-success.html", ReportData{
+		Timestamp:         job.Timestamp,
+		NotificationCount: s.notifications.UnreadCount(),
+	})
+}
+
+// handleNotifications returns the notifications list, truncated to
+// features.notifications_display_limit (newest first) unless the caller
+// passes ?all=true for the full list, e.g. from the modal's "view all" link.
+func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
+	s.renderTemplate(w, r, "notifications.html", s.notificationsData(r.URL.Query().Get("all") == "true"))
+}
+
+// notificationsData builds the notifications modal's data, truncating to
+// features.notifications_display_limit unless showAll is set. Shared by
+// handleNotifications and handleDeleteNotification, which both re-render the
+// same list partial.
+func (s *Server) notificationsData(showAll bool) NotificationsData {
+	all := s.notifications.GetAll()
+	limit := s.config().Features.NotificationsDisplayLimit
+
+	data := NotificationsData{
+		Notifications: all,
+		Count:         s.notifications.Count(),
+		UnreadCount:   s.notifications.UnreadCount(),
+	}
+	if limit > 0 && len(all) > limit && !showAll {
+		data.Notifications = all[:limit]
+		data.Limited = true
+	}
+	return data
+}
+
+// handleMarkNotificationsRead marks every notification read and returns the
+// updated badge fragment, e.g. for the notifications modal's "Mark all read"
+// button to swap into the bell.
+func (s *Server) handleMarkNotificationsRead(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.notifications.MarkAllRead()
+
+	s.renderTemplate(w, r, "notification-badge.html", NotificationBadgeData{
+		NotificationCount: s.notifications.UnreadCount(),
+	})
+}
+
+// handleDeleteNotification dismisses a single notification, so users can
+// clear individual stale messages without wiping everything via Clear.
+// Path: DELETE /notifications/{id}.
+func (s *Server) handleDeleteNotification(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	idStr := strings.TrimPrefix(r.URL.Path, s.route("/notifications/"))
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	if !s.notifications.Delete(id) {
+		s.writeError(w, r, "Notification not found", http.StatusNotFound)
+		return
+	}
+
+	s.renderTemplate(w, r, "notifications.html", s.notificationsData(false))
+}
+
+// MetadataResponse holds the metadata endpoint response for stale tab detection
+type MetadataResponse struct {
+	Version     string                 `json:"version"`
+	Commit      string                 `json:"commit"`
+	CommitDate  string                 `json:"commit_date"`
+	Environment string                 `json:"environment"`
+	Features    map[string]interface{} `json:"features"`
+}
+
+// handleMetadata returns version, environment, and feature flags as JSON
+// Used for stale tab detection - clients poll this to detect server updates
+func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
+	versionInfo := version.Get()
+
+	// Get environment from env var, default to "production"
+	environment := getEnvironment()
+
+	// Build features map from config
+	features := map[string]interface{}{
+		"avg_refresh_interval_ms": s.config().Features.AvgRefreshIntervalMs,
+	}
+
+	response := MetadataResponse{
+		Version:     versionInfo.Version,
+		Commit:      versionInfo.Commit,
+		CommitDate:  versionInfo.CommitDate,
+		Environment: environment,
+		Features:    features,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/metadata", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
-	return delays
 }
 
-// handleIndex renders the main dashboard page
-func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
-	if r.URL.Path != "/" {
-		http.NotFound(w, r)
-		return
-	}
+// VersionCheckResponse holds the version/check endpoint response, a
+// lighter-weight alternative to diffing the full MetadataResponse when a
+// client only cares whether it's running a stale build.
+type VersionCheckResponse struct {
+	UpdateAvailable bool   `json:"update_available"`
+	ServerVersion   string `json:"server_version"`
+	ServerCommit    string `json:"server_commit"`
+}
 
+// handleVersionCheck compares the client's known commit (the ?client= query
+// param) against the server's build commit, so callers can prompt for a
+// reload without diffing the whole metadata payload themselves. A missing or
+// empty client param is treated as unknown and always reports an update.
+func (s *Server) handleVersionCheck(w http.ResponseWriter, r *http.Request) {
 	versionInfo := version.Get()
-	data := PageData{
-		Title:             "Dashboard",
-		NotificationCount: s.notifications.Count(),
-		AvgRefreshMs:      s.cfg.Features.AvgRefreshIntervalMs,
-		Version:           versionInfo.Version,
-		Commit:            versionInfo.Commit,
-		CommitDate:        versionInfo.CommitDate,
-		RequestFeatureURL: s.cfg.Links.RequestFeatureURL,
-		ReportBugURL:      s.cfg.Links.ReportBugURL,
+	clientCommit := r.URL.Query().Get("client")
+
+	response := VersionCheckResponse{
+		UpdateAvailable: clientCommit != versionInfo.Commit,
+		ServerVersion:   versionInfo.Version,
+		ServerCommit:    versionInfo.Commit,
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "layout.html", data); err != nil {
-		slog.Error("template_error", "template", "layout.html", "error", err)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/version/check", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
-// handleTicker returns the full crypto price table (initial load)
-func (s *Server) handleTicker(w http.ResponseWriter, r *http.Request) {
-	coins, err := s.coinService.GetPrices()
-	if err != nil {
-		http.Error(w, "Failed to fetch prices", http.StatusInternalServerError)
+// RevokeSessionsRequest is the JSON body for POST /admin/sessions/revoke
+type RevokeSessionsRequest struct {
+	Username string `json:"username"`
+}
+
+// RevokeSessionsResponse reports how many sessions were revoked
+type RevokeSessionsResponse struct {
+	Revoked int `json:"revoked"`
+}
+
+// handleAdminRevokeSessions invalidates every active session for a user, via
+// session.Store.DeleteByUsername. Intended for logging a user out of every
+// device at once, e.g. after a suspected credential leak, without needing
+// to restart the process to clear its in-memory session store.
+// The app currently has a single authenticated principal (the Basic Auth
+// user), so this is gated the same way every other non-public endpoint is
+// - there's no separate admin/viewer role to distinguish between yet.
+func (s *Server) handleAdminRevokeSessions(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	coinData := make([]CoinRowData, len(coins))
-	for i, c := range coins {
-		coinData[i] = CoinRowData{
-			ID:          c.ID,
-			DisplayName: c.DisplayName,
-			Price:       c.Price,
-			Change24h:   c.Change24h,
-			Delays:      s.generateDelayQueue(),
-		}
+	var req RevokeSessionsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Username == "" {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
 	}
 
-	data := TickerData{Coins: coinData}
+	revoked := s.sessions.DeleteByUsername(req.Username)
+	middleware.Logger(r.Context()).Info("sessions_revoked", "username", req.Username, "count", revoked, "ip", r.RemoteAddr)
+	s.audit.Add(r.RemoteAddr, "sessions.revoke", fmt.Sprintf("username=%s count=%d", req.Username, revoked))
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "ticker.html", data); err != nil {
-		slog.Error("template_error", "template", "ticker.html", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(RevokeSessionsResponse{Revoked: revoked})
 }
 
-// handleTickerCoin returns a single coin row (async refresh per coin)
-func (s *Server) handleTickerCoin(w http.ResponseWriter, r *http.Request) {
-	// Extract coin ID from path: /ticker/{coinId}
-	coinID := strings.TrimPrefix(r.URL.Path, "/ticker/")
-	if coinID == "" {
-		http.NotFound(w, r)
+// handleAdminAuditExport streams the audit log as JSON or CSV for
+// compliance/download, gated the same way every other non-public endpoint
+// is. Path: GET /admin/audit/export?format=csv|json&from=&to=. from/to are
+// RFC3339 timestamps; either may be omitted to leave that end of the range
+// unbounded. Entries are rendered through Entry.Redacted, the same
+// redaction any future audit listing would use.
+func (s *Server) handleAdminAuditExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
 	}
 
-	coin, err := s.coinService.GetCoin(coinID)
-	if err != nil {
-		http.Error(w, "Coin not found", http.StatusNotFound)
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "json"
+	}
+	if format != "json" && format != "csv" {
+		http.Error(w, "format must be \"json\" or \"csv\"", http.StatusBadRequest)
 		return
 	}
 
-	data := CoinRowData{
-		ID:          coin.ID,
-		DisplayName: coin.DisplayName,
-		Price:       coin.Price,
-		Change24h:   coin.Change24h,
-		Delays:      s.generateDelayQueue(),
+	var from, to time.Time
+	if raw := r.URL.Query().Get("from"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "from must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		from = parsed
 	}
-
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "ticker_row.html", data); err != nil {
-		slog.Error("template_error", "template", "ticker_row.html", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	if raw := r.URL.Query().Get("to"); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			http.Error(w, "to must be an RFC3339 timestamp", http.StatusBadRequest)
+			return
+		}
+		to = parsed
 	}
-}
 
-// handleSearch filters coins by search query
-func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
-	query := r.URL.Query().Get("search")
-
-	coins, err := s.coinService.SearchCoins(query)
-	if err != nil {
-		http.Error(w, "Failed to search", http.StatusInternalServerError)
+	entries := s.audit.GetRange(from, to)
+	filename := fmt.Sprintf("audit-export-%s.%s", time.Now().UTC().Format("20060102T150405Z"), format)
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv")
+		writer := csv.NewWriter(w)
+		writer.Write([]string{"id", "timestamp", "actor", "action", "detail"})
+		for _, e := range entries {
+			writer.Write([]string{
+				strconv.Itoa(e.ID),
+				e.Timestamp.Format(time.RFC3339),
+				e.Actor,
+				e.Action,
+				e.Detail,
+			})
+		}
+		writer.Flush()
 		return
 	}
 
-	coinData := make([]CoinRowData, len(coins))
-	for i, c := range coins {
-		coinData[i] = CoinRowData{
-			ID:          c.ID,
-			DisplayName: c.DisplayName,
-			Price:       c.Price,
-			Change24h:   c.Change24h,
-			Delays:      s.generateDelayQueue(),
-		}
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(entries); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/admin/audit/export", "error", err)
 	}
+}
 
-	data := TickerData{Coins: coinData}
+// AnnouncementRequest is the JSON body for POST /admin/announcement.
+type AnnouncementRequest struct {
+	Text     string `json:"text"`
+	Severity string `json:"severity"`
+	// ExpiresAt is an optional RFC3339 timestamp; the announcement is
+	// cleared automatically once reached. Omit for one that stays active
+	// until explicitly cleared.
+	ExpiresAt string `json:"expires_at"`
+}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "ticker.html", data); err != nil {
-		slog.Error("template_error", "template", "ticker.html", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-	}
+// announcementSeverities are the values layout.html knows how to style.
+var announcementSeverities = map[string]bool{
+	announcement.SeverityInfo:     true,
+	announcement.SeverityWarning:  true,
+	announcement.SeverityCritical: true,
 }
 
-// handleGenerateReport simulates a slow admin operation
-func (s *Server) handleGenerateReport(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
-		return
-	}
+// handleAdminAnnouncement sets (POST) or clears (DELETE) the dashboard
+// banner, gated the same way every other non-public endpoint is. The
+// announcement lives only in memory, so it's lost on restart - operators
+// posting a longer-lived notice should re-set it after a deploy.
+func (s *Server) handleAdminAnnouncement(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req AnnouncementRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Text == "" {
+			http.Error(w, "Invalid request", http.StatusBadRequest)
+			return
+		}
+		if req.Severity == "" {
+			req.Severity = announcement.SeverityInfo
+		}
+		if !announcementSeverities[req.Severity] {
+			http.Error(w, "severity must be \"info\", \"warning\", or \"critical\"", http.StatusBadRequest)
+			return
+		}
 
-	// Simulate slow backend operation (3 seconds)
-	time.Sleep(3 * time.Second)
+		var expiresAt time.Time
+		if req.ExpiresAt != "" {
+			parsed, err := time.Parse(time.RFC3339, req.ExpiresAt)
+			if err != nil {
+				http.Error(w, "expires_at must be an RFC3339 timestamp", http.StatusBadRequest)
+				return
+			}
+			expiresAt = parsed
+		}
 
-	timestamp := time.Now().Format("20060102_150405")
-	s.notifications.Add("Report Ready", "Compliance report "+timestamp+" generated successfully")
+		s.announcements.Set(req.Text, req.Severity, expiresAt)
+		middleware.Logger(r.Context()).Info("announcement_set", "severity", req.Severity, "expires_at", req.ExpiresAt, "ip", r.RemoteAddr)
+		s.audit.Add(r.RemoteAddr, "announcement.set", fmt.Sprintf("severity=%s", req.Severity))
+		w.WriteHeader(http.StatusOK)
 
-	data := ReportData{
-		Timestamp:         timestamp,
-		NotificationCount: s.notifications.Count(),
-	}
+	case http.MethodDelete:
+		s.announcements.Clear()
+		middleware.Logger(r.Context()).Info("announcement_cleared", "ip", r.RemoteAddr)
+		s.audit.Add(r.RemoteAddr, "announcement.clear", "")
+		w.WriteHeader(http.StatusOK)
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "report-success.html", data); err != nil {
-		slog.Error("template_error", "template", "report-success.html", "error", err)
-		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 	}
 }
 
-// handleNotifications returns the notifications list
-func (s *Server) handleNotifications(w http.ResponseWriter, r *http.Request) {
-	data := NotificationsData{
+// CoinCacheSnapshot reports the coin cache's size and staleness, as
+// returned by CoinService.CacheStats.
+type CoinCacheSnapshot struct {
+	Size       int     `json:"size"`
+	AgeSeconds float64 `json:"age_seconds"`
+}
+
+// DebugSnapshot is a single consistent capture of server state for support
+// bundles, so an engineer diagnosing an issue doesn't have to correlate
+// several endpoints by hand.
+type DebugSnapshot struct {
+	Version       string                       `json:"version"`
+	Commit        string                       `json:"commit"`
+	CommitDate    string                       `json:"commit_date"`
+	Environment   string                       `json:"environment"`
+	Uptime        string                       `json:"uptime"`
+	ConfigSource  string                       `json:"config_source"`
+	Sessions      []session.SessionSummary     `json:"sessions"`
+	Notifications []notifications.Notification `json:"notifications"`
+	CoinCache     CoinCacheSnapshot            `json:"coin_cache"`
+}
+
+// DebugSnapshot gathers a point-in-time view of every store an engineer
+// would need to diagnose an issue: sanitized sessions, notifications, coin
+// cache stats, config source, version, and uptime. Each section is read
+// through its own store's already-locked accessor (Summaries, GetAll,
+// CacheStats), so this briefly takes each store's lock in turn rather than
+// introducing a new cross-store lock the rest of the app doesn't have.
+func (s *Server) DebugSnapshot() DebugSnapshot {
+	versionInfo := version.Get()
+	cacheSize, cacheAge := s.coinService.CacheStats()
+
+	return DebugSnapshot{
+		Version:       versionInfo.Version,
+		Commit:        versionInfo.Commit,
+		CommitDate:    versionInfo.CommitDate,
+		Environment:   getEnvironment(),
+		Uptime:        time.Since(s.startTime).Round(time.Second).String(),
+		ConfigSource:  s.configSource,
+		Sessions:      s.sessions.Summaries(),
 		Notifications: s.notifications.GetAll(),
-		Count:         s.notifications.Count(),
+		CoinCache: CoinCacheSnapshot{
+			Size:       cacheSize,
+			AgeSeconds: cacheAge,
+		},
+	}
+}
+
+// handleAdminDebugSnapshot returns DebugSnapshot as JSON, for support
+// bundles. Gated the same way every other admin endpoint is.
+func (s *Server) handleAdminDebugSnapshot(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "notifications.html", data); err != nil {
-		slog.Error("template_error", "template", "notifications.html", "error", err)
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(s.DebugSnapshot()); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/admin/debug-snapshot", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
-// MetadataResponse holds the metadata endpoint response for stale tab detection
-type MetadataResponse struct {
-	Version     string                 `json:"version"`
-	Commit      string                 `json:"commit"`
-	CommitDate  string                 `json:"commit_date"`
-	Environment string                 `json:"environment"`
-	Features    map[string]interface{} `json:"features"`
+// TemplateCheckResult reports whether a single template rendered
+// successfully during a selftest sweep.
+type TemplateCheckResult struct {
+	Name  string `json:"name"`
+	Pass  bool   `json:"pass"`
+	Error string `json:"error,omitempty"`
 }
 
-// handleMetadata returns version, environment, and feature flags as JSON
-// Used for stale tab detection - clients poll this to detect server updates
-func (s *Server) handleMetadata(w http.ResponseWriter, r *http.Request) {
-	versionInfo := version.Get()
+// SelftestCacheState reports the coin service's price cache state.
+type SelftestCacheState struct {
+	Size       int     `json:"size"`
+	AgeSeconds float64 `json:"age_seconds"`
+}
 
-	// Get environment from env var, default to "production"
-	environment := getEnvironment()
+// SelftestResponse is the JSON response from handleSelftest.
+type SelftestResponse struct {
+	Status     string                `json:"status"` // "ok" or "degraded"
+	Templates  []TemplateCheckResult `json:"templates"`
+	CacheState SelftestCacheState    `json:"cache_state"`
+}
 
-	// Build features map from config
-	features := map[string]interface{}{
-		"avg_refresh_interval_ms": s.cfg.Features.AvgRefreshIntervalMs,
+// selftestChecks lists the templates the app actually renders in
+// production, paired with representative data, so handleSelftest can
+// execute each one into a discard writer and catch rendering regressions
+// (e.g. after a hot config reload) without waiting for a real request.
+func (s *Server) selftestChecks() []struct {
+	name string
+	data interface{}
+} {
+	return []struct {
+		name string
+		data interface{}
+	}{
+		{"layout.html", PageData{Title: "Dashboard", BasePath: s.config().Server.BasePath}},
+		{"ticker.html", TickerData{Coins: []CoinRowData{{ID: "bitcoin", DisplayName: "Bitcoin", Price: 50000, Change24h: 1.5, Delays: []int{100}}}}},
+		{"ticker_row.html", CoinRowData{ID: "bitcoin", DisplayName: "Bitcoin", Price: 50000, Change24h: 1.5, Delays: []int{100}}},
+		{"report-success.html", ReportData{Timestamp: time.Now().Format(time.RFC3339), NotificationCount: 0}},
+		{"report-pending.html", ReportPendingData{JobID: "selftest"}},
+		{"notifications.html", NotificationsData{Notifications: nil, Count: 0}},
+		{"notification-badge.html", NotificationBadgeData{NotificationCount: 0}},
+		{"login.html", LoginPageData{BasePath: s.config().Server.BasePath}},
+	}
+}
+
+// handleSelftest executes every production template against representative
+// data and reports pass/fail per template, plus the coin service's cache
+// state. It's an admin-only diagnostic, gated the same way as the other
+// non-public endpoints since the app has a single authenticated principal.
+func (s *Server) handleSelftest(w http.ResponseWriter, r *http.Request) {
+	checks := s.selftestChecks()
+	results := make([]TemplateCheckResult, len(checks))
+	status := "ok"
+
+	for i, c := range checks {
+		err := s.templates.ExecuteTemplate(io.Discard, c.name, c.data)
+		results[i] = TemplateCheckResult{Name: c.name, Pass: err == nil}
+		if err != nil {
+			results[i].Error = err.Error()
+			status = "degraded"
+		}
 	}
 
-	response := MetadataResponse{
-		Version:     versionInfo.Version,
-		Commit:      versionInfo.Commit,
-		CommitDate:  versionInfo.CommitDate,
-		Environment: environment,
-		Features:    features,
+	size, ageSeconds := s.coinService.CacheStats()
+
+	resp := SelftestResponse{
+		Status:     status,
+		Templates:  results,
+		CacheState: SelftestCacheState{Size: size, AgeSeconds: ageSeconds},
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	if err := json.NewEncoder(w).Encode(response); err != nil {
-		slog.Error("json_encode_error", "endpoint", "/metadata", "error", err)
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/selftest", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
@@ -395,11 +2231,18 @@ func getEnvironment() string {
 
 // HealthResponse holds the health endpoint response for observability
 type HealthResponse struct {
-	Status     string  `json:"status"`
-	Uptime     string  `json:"uptime"`
-	Goroutines int     `json:"goroutines"`
-	MemoryMB   float64 `json:"memory_mb"`
-	GoVersion  string  `json:"go_version"`
+	Status      string  `json:"status"`
+	Uptime      string  `json:"uptime"`
+	Goroutines  int     `json:"goroutines"`
+	MemoryMB    float64 `json:"memory_mb"`
+	GoVersion   string  `json:"go_version"`
+	Version     string  `json:"version"`
+	Commit      string  `json:"commit"`
+	CommitDate  string  `json:"commit_date"`
+	HeapObjects uint64  `json:"heap_objects"`
+	GCPauseMs   float64 `json:"gc_pause_ms"`
+	NumGC       uint32  `json:"num_gc"`
+	SysMB       float64 `json:"sys_mb"`
 }
 
 // handleHealth returns runtime stats for monitoring and Kubernetes probes
@@ -408,35 +2251,145 @@ func (s *Server) handleHealth(w http.ResponseWriter, r *http.Request) {
 	runtime.ReadMemStats(&memStats)
 
 	uptime := time.Since(s.startTime)
+	versionInfo := version.Get()
 
 	response := HealthResponse{
-		Status:     "ok",
-		Uptime:     uptime.Round(time.Second).String(),
-		Goroutines: runtime.NumGoroutine(),
-		MemoryMB:   float64(memStats.Alloc) / 1024 / 1024,
-		GoVersion:  runtime.Version(),
+		Status:      "ok",
+		Uptime:      uptime.Round(time.Second).String(),
+		Goroutines:  runtime.NumGoroutine(),
+		MemoryMB:    float64(memStats.Alloc) / 1024 / 1024,
+		GoVersion:   runtime.Version(),
+		Version:     versionInfo.Version,
+		Commit:      versionInfo.Commit,
+		CommitDate:  versionInfo.CommitDate,
+		HeapObjects: memStats.HeapObjects,
+		GCPauseMs:   float64(lastGCPause(&memStats)) / float64(time.Millisecond),
+		NumGC:       memStats.NumGC,
+		SysMB:       float64(memStats.Sys) / 1024 / 1024,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	if err := json.NewEncoder(w).Encode(response); err != nil {
-		slog.Error("json_encode_error", "endpoint", "/health", "error", err)
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/health", "error", err)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+	}
+}
+
+// lastGCPause returns the duration of the most recent garbage collection
+// pause from memStats.PauseNs, a ring buffer of the last 256 pauses indexed
+// by NumGC. Zero if no GC has run yet.
+func lastGCPause(memStats *runtime.MemStats) time.Duration {
+	if memStats.NumGC == 0 {
+		return 0
+	}
+	return time.Duration(memStats.PauseNs[(memStats.NumGC+255)%256])
+}
+
+// DependencyStatus reports the health of a single upstream dependency
+type DependencyStatus struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"` // "ok" or "down"
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+}
+
+// APIHealthResponse holds the verbose /api/health response
+type APIHealthResponse struct {
+	Status       string             `json:"status"` // "ok" or "degraded"
+	Dependencies []DependencyStatus `json:"dependencies"`
+	CheckedAt    time.Time          `json:"checked_at"`
+}
+
+// handleAPIHealth returns the cheap /health payload by default, or a
+// verbose payload with live dependency checks when ?verbose=1 is set.
+// Verbose results are cached briefly so polling doesn't hammer upstream.
+func (s *Server) handleAPIHealth(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("verbose") != "1" {
+		s.handleHealth(w, r)
+		return
+	}
+
+	resp := s.verboseHealth(r.Context())
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", "/api/health", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
 	}
 }
 
+// verboseHealth runs (or reuses a cached) dependency check sweep.
+func (s *Server) verboseHealth(ctx context.Context) APIHealthResponse {
+	s.apiHealth.mu.Lock()
+	defer s.apiHealth.mu.Unlock()
+
+	if time.Since(s.apiHealth.checkedAt) < apiHealthCacheTTL {
+		return s.apiHealth.result
+	}
+
+	deps := []DependencyStatus{s.checkCoinGecko(ctx)}
+
+	status := "ok"
+	for _, d := range deps {
+		if d.Status != "ok" {
+			status = "degraded"
+			break
+		}
+	}
+
+	result := APIHealthResponse{
+		Status:       status,
+		Dependencies: deps,
+		CheckedAt:    time.Now(),
+	}
+
+	s.apiHealth.result = result
+	s.apiHealth.checkedAt = time.Now()
+
+	return result
+}
+
+// checkCoinGecko pings the upstream CoinGecko API with a short timeout.
+func (s *Server) checkCoinGecko(ctx context.Context) DependencyStatus {
+	ctx, cancel := context.WithTimeout(ctx, apiHealthCheckTimeout)
+	defer cancel()
+
+	start := time.Now()
+	err := s.coinService.Ping(ctx)
+	latency := time.Since(start)
+
+	if err != nil {
+		return DependencyStatus{
+			Name:      "coingecko",
+			Status:    "down",
+			LatencyMs: float64(latency.Microseconds()) / 1000.0,
+			Error:     err.Error(),
+		}
+	}
+
+	return DependencyStatus{
+		Name:      "coingecko",
+		Status:    "ok",
+		LatencyMs: float64(latency.Microseconds()) / 1000.0,
+	}
+}
+
 // handleLogin serves the login page
 func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
 	// If user is already authenticated, redirect to home
 	if sess := s.getSessionFromRequest(r); sess != nil {
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		http.Redirect(w, r, s.route("/"), http.StatusSeeOther)
 		return
 	}
 
-	w.Header().Set("Content-Type", "text/html; charset=utf-8")
-	if err := s.templates.ExecuteTemplate(w, "login.html", nil); err != nil {
-		slog.Error("template_error", "template", "login.html", "error", err)
+	csrfToken, err := s.csrfToken(w, r)
+	if err != nil {
+		middleware.Logger(r.Context()).Error("csrf_token_error", "error", err)
 		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
 	}
+
+	s.renderTemplate(w, r, "login.html", LoginPageData{BasePath: s.config().Server.BasePath, CSRFToken: csrfToken})
 }
 
 // AuthRequest holds login request data
@@ -470,6 +2423,17 @@ func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !s.validateCSRFToken(r) {
+		middleware.Logger(r.Context()).Warn("csrf_validation_failed", "path", r.URL.Path, "ip", r.RemoteAddr)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(AuthResponse{
+			Success: false,
+			Error:   "Invalid or missing CSRF token",
+		})
+		return
+	}
+
 	username := r.FormValue("username")
 	password := r.FormValue("password")
 
@@ -480,7 +2444,8 @@ func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
 	// Validate credentials
 	// First check username (simple equality is fine for username)
 	if username != envUsername {
-		slog.Warn("login_failed", "username", username, "ip", r.RemoteAddr, "reason", "invalid_username")
+		middleware.Logger(r.Context()).Warn("login_failed", "username", username, "ip", r.RemoteAddr, "reason", "invalid_username")
+		middleware.RecordAuthFailure("invalid_username")
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(AuthResponse{
@@ -492,7 +2457,8 @@ func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
 
 	// Then verify password hash using bcrypt (constant-time comparison built-in)
 	if err := bcrypt.CompareHashAndPassword([]byte(envPasswordHash), []byte(password)); err != nil {
-		slog.Warn("login_failed", "username", username, "ip", r.RemoteAddr, "reason", "invalid_password")
+		middleware.Logger(r.Context()).Warn("login_failed", "username", username, "ip", r.RemoteAddr, "reason", "invalid_password")
+		middleware.RecordAuthFailure("invalid_password")
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusUnauthorized)
 		json.NewEncoder(w).Encode(AuthResponse{
@@ -502,10 +2468,22 @@ func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	s.warnIfBcryptCostBelowTarget(r, envPasswordHash)
+
 	// Create session
 	sess, err := s.sessions.Create(username)
 	if err != nil {
-		slog.Error("session_create_error", "error", err)
+		if errors.Is(err, session.ErrSessionLimitReached) {
+			middleware.Logger(r.Context()).Warn("login_rejected", "username", username, "ip", r.RemoteAddr, "reason", "session_limit_reached")
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusTooManyRequests)
+			json.NewEncoder(w).Encode(AuthResponse{
+				Success: false,
+				Error:   "You have reached the maximum number of active sessions. Please log out of another device and try again.",
+			})
+			return
+		}
+		middleware.Logger(r.Context()).Error("session_create_error", "error", err)
 		w.Header().Set("Content-Type", "application/json")
 		w.WriteHeader(http.StatusInternalServerError)
 		json.NewEncoder(w).Encode(AuthResponse{
@@ -516,23 +2494,23 @@ func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Set session cookie
+	secure := middleware.IsSecureRequest(r, s.config().Security.TrustedProxies)
 	http.SetCookie(w, &http.Cookie{
-		Name:     session.GetCookieName(),
+		Name:     s.sessionCookieName(secure),
 		Value:    sess.ID,
-		Path:     "/",
+		Path:     s.route("/"),
 		HttpOnly: true,
-		Secure:   r.TLS != nil, // Only send over HTTPS if available
+		Secure:   secure, // Only send over HTTPS if available
 		SameSite: http.SameSiteLaxMode,
 		Expires:  sess.ExpiresAt,
 	})
 
-	slog.Info("login_success", "username", username, "session_id", sess.ID, "ip", r.RemoteAddr)
+	middleware.Logger(r.Context()).Info("login_success", "username", username, "session_id", sess.ID, "ip", r.RemoteAddr)
+	middleware.RecordAuthSuccess()
 
-	// Get redirect target from query param or default to /
-	redirect := r.URL.Query().Get("redirect")
-	if redirect == "" {
-		redirect = "/"
-	}
+	// Get redirect target from query param, falling back to / if it's
+	// missing or fails validation.
+	redirect := s.sanitizeRedirect(r.URL.Query().Get("redirect"))
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(AuthResponse{
@@ -541,10 +2519,86 @@ func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// sanitizeRedirect validates a post-login redirect target, returning it
+// unchanged if allowed or "/" (routed under BasePath) otherwise. When
+// Security.AllowedRedirects is non-empty, only exact matches from that list
+// are honored; otherwise any local path is allowed.
+func (s *Server) sanitizeRedirect(redirect string) string {
+	allowed := s.config().Security.AllowedRedirects
+	if len(allowed) > 0 {
+		if slices.Contains(allowed, redirect) {
+			return redirect
+		}
+		return s.route("/")
+	}
+
+	if isLocalRedirectPath(redirect) {
+		return redirect
+	}
+	return s.route("/")
+}
+
+// isLocalRedirectPath reports whether redirect is safe to send a client to
+// without risking an open redirect: it must be a path on this site, not a
+// scheme-relative or absolute URL to somewhere else (e.g. "//evil.com" or
+// "https://evil.com").
+func isLocalRedirectPath(redirect string) bool {
+	if redirect == "" || redirect[0] != '/' {
+		return false
+	}
+	if len(redirect) > 1 && redirect[1] == '/' {
+		return false
+	}
+	u, err := url.Parse(redirect)
+	if err != nil {
+		return false
+	}
+	return u.Host == "" && u.Scheme == ""
+}
+
+// warnIfBcryptCostBelowTarget logs a warning when a successfully verified
+// password hash was hashed at a lower bcrypt cost than currently configured.
+// Since BASIC_AUTH_PASSWORD_HASH lives in an environment variable, there is
+// nowhere to persist a transparently rehashed value, so we can only flag it
+// for an operator to regenerate via `coinops genenv`.
+func (s *Server) warnIfBcryptCostBelowTarget(r *http.Request, hash string) {
+	targetCost := s.config().Security.BasicAuth.BcryptCost
+	if targetCost <= 0 {
+		return
+	}
+
+	cost, err := bcrypt.Cost([]byte(hash))
+	if err != nil {
+		return
+	}
+
+	if cost < targetCost {
+		middleware.Logger(r.Context()).Warn("password_hash_below_target_cost",
+			"current_cost", cost,
+			"target_cost", targetCost,
+			"hint", "regenerate credentials with 'coinops genenv' to upgrade the bcrypt cost",
+		)
+	}
+}
+
 // handleLogout destroys the session and redirects to login
 func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if !s.validateCSRFToken(r) {
+		middleware.Logger(r.Context()).Warn("csrf_validation_failed", "path", r.URL.Path, "ip", r.RemoteAddr)
+		http.Error(w, "Invalid or missing CSRF token", http.StatusForbidden)
+		return
+	}
+
+	secure := middleware.IsSecureRequest(r, s.config().Security.TrustedProxies)
+	cookieName := s.sessionCookieName(secure)
+
 	// Get session from cookie
-	cookie, err := r.Cookie(session.GetCookieName())
+	cookie, err := r.Cookie(cookieName)
 	if err == nil {
 		// Delete session
 		s.sessions.Delete(cookie.Value)
@@ -552,17 +2606,18 @@ func (s *Server) handleLogout(w http.ResponseWriter, r *http.Request) {
 
 	// Clear session cookie
 	http.SetCookie(w, &http.Cookie{
-		Name:     session.GetCookieName(),
+		Name:     cookieName,
 		Value:    "",
-		Path:     "/",
+		Path:     s.route("/"),
 		HttpOnly: true,
+		Secure:   secure,
 		MaxAge:   -1, // Delete cookie
 	})
 
-	slog.Info("logout", "ip", r.RemoteAddr)
+	middleware.Logger(r.Context()).Info("logout", "ip", r.RemoteAddr)
 
 	// Redirect to login page
-	http.Redirect(w, r, "/login", http.StatusSeeOther)
+	http.Redirect(w, r, s.route("/login"), http.StatusSeeOther)
 }
 
 // sessionAuthMiddleware checks for valid session or Basic Auth
@@ -575,13 +2630,14 @@ func (s *Server) sessionAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// Skip if auth is disabled
-		if !s.cfg.Security.BasicAuth.Enabled {
+		if !s.config().Security.BasicAuth.Enabled {
 			next.ServeHTTP(w, r)
 			return
 		}
 
 		// Check for valid session first
 		if sess := s.getSessionFromRequest(r); sess != nil {
+			s.renewSessionCookieIfNeeded(w, r, sess)
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -592,9 +2648,13 @@ func (s *Server) sessionAuthMiddleware(next http.Handler) http.Handler {
 
 		if envUsername != "" && envPasswordHash != "" {
 			reqUser, reqPass, ok := r.BasicAuth()
-			if ok && reqUser == envUsername {
-				// Verify password using bcrypt
-				if err := bcrypt.CompareHashAndPassword([]byte(envPasswordHash), []byte(reqPass)); err == nil {
+			if ok {
+				if reqUser != envUsername {
+					middleware.RecordAuthFailure("invalid_username")
+				} else if err := bcrypt.CompareHashAndPassword([]byte(envPasswordHash), []byte(reqPass)); err != nil {
+					middleware.RecordAuthFailure("invalid_password")
+				} else {
+					middleware.RecordAuthSuccess()
 					next.ServeHTTP(w, r)
 					return
 				}
@@ -602,16 +2662,29 @@ func (s *Server) sessionAuthMiddleware(next http.Handler) http.Handler {
 		}
 
 		// No valid authentication - redirect to login
-		slog.Warn("auth_required", "path", r.URL.Path, "ip", r.RemoteAddr)
-
-		// For AJAX requests, return 401
-		if r.Header.Get("X-Requested-With") == "XMLHttpRequest" || r.Header.Get("HX-Request") == "true" {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		middleware.Logger(r.Context()).Warn("auth_required", "path", r.URL.Path, "ip", r.RemoteAddr)
+
+		// Programmatic clients - AJAX/HTMX requests, JSON Accept, and /api/
+		// paths regardless of headers - always get a JSON 401 with a
+		// WWW-Authenticate hint instead of an HTML redirect they can't follow.
+		if s.isJSONErrorPath(r.URL.Path) ||
+			strings.Contains(r.Header.Get("Accept"), "application/json") ||
+			r.Header.Get("X-Requested-With") == "XMLHttpRequest" ||
+			r.Header.Get("HX-Request") == "true" {
+			w.Header().Set("WWW-Authenticate", `Basic realm="restricted"`)
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			if err := json.NewEncoder(w).Encode(ErrorResponse{
+				Error:     "Unauthorized",
+				RequestID: middleware.GetRequestID(r.Context()),
+			}); err != nil {
+				middleware.Logger(r.Context()).Error("json_encode_error", "endpoint", r.URL.Path, "error", err)
+			}
 			return
 		}
 
 		// For regular requests, redirect to login with return URL
-		loginURL := "/login?redirect=" + r.URL.Path
+		loginURL := s.route("/login") + "?redirect=" + r.URL.Path
 		http.Redirect(w, r, loginURL, http.StatusSeeOther)
 	})
 }
@@ -619,11 +2692,13 @@ func (s *Server) sessionAuthMiddleware(next http.Handler) http.Handler {
 // isPublicEndpoint returns true if the path doesn't require authentication
 func (s *Server) isPublicEndpoint(path string) bool {
 	publicPaths := []string{
-		"/login",
-		"/auth",
-		"/logout",
-		"/assets/",
-		"/health",
+		s.route("/login"),
+		s.route("/auth"),
+		s.route("/logout"),
+		s.route("/assets/"),
+		s.route("/health"),
+		s.route("/api/health"),
+		s.route("/metrics"),
 	}
 
 	for _, publicPath := range publicPaths {
@@ -635,12 +2710,41 @@ func (s *Server) isPublicEndpoint(path string) bool {
 	return false
 }
 
+// sessionCookieName returns the name the session cookie should be set or
+// read under for a request with the given security status, honoring
+// security.session.host_prefix_cookie.
+func (s *Server) sessionCookieName(secure bool) string {
+	return session.GetCookieName(secure && s.config().Security.Session.HostPrefixCookie)
+}
+
 // getSessionFromRequest retrieves the session from the request cookie
 func (s *Server) getSessionFromRequest(r *http.Request) *session.Session {
-	cookie, err := r.Cookie(session.GetCookieName())
+	cookie, err := r.Cookie(s.sessionCookieName(middleware.IsSecureRequest(r, s.config().Security.TrustedProxies)))
 	if err != nil {
 		return nil
 	}
 
 	return s.sessions.Get(cookie.Value)
 }
+
+// renewSessionCookieIfNeeded re-sends sess's cookie with a later Expires
+// when the store just renewed it under sliding expiration, so the browser's
+// copy stays in sync with the server's. It's a no-op when sliding
+// expiration is disabled or sess wasn't close enough to expiring.
+func (s *Server) renewSessionCookieIfNeeded(w http.ResponseWriter, r *http.Request, sess *session.Session) {
+	expiresAt, renewed := s.sessions.Touch(sess.ID)
+	if !renewed {
+		return
+	}
+
+	secure := middleware.IsSecureRequest(r, s.config().Security.TrustedProxies)
+	http.SetCookie(w, &http.Cookie{
+		Name:     s.sessionCookieName(secure),
+		Value:    sess.ID,
+		Path:     s.route("/"),
+		HttpOnly: true,
+		Secure:   secure,
+		SameSite: http.SameSiteLaxMode,
+		Expires:  expiresAt,
+	})
+}
@@ -2,7 +2,11 @@ package session
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
 	"sync"
 	"time"
 
@@ -10,12 +14,28 @@ import (
 )
 
 const (
-	sessionIDLength   = 32
-	sessionTimeout    = 24 * time.Hour
-	cleanupInterval   = 1 * time.Hour
-	sessionCookieName = "coinops_session"
+	sessionIDLength = 32
+	sessionTimeout  = 24 * time.Hour
+	cleanupInterval = 1 * time.Hour
+	// slidingRenewalThreshold is the fraction of a session's lifetime at or
+	// below which the remaining time must fall before Touch renews it, when
+	// sliding expiration is enabled.
+	slidingRenewalThreshold = 0.5
+	sessionCookieName       = "coinops_session"
+	// hostCookiePrefix marks a cookie with the __Host- prefix, which browsers
+	// only accept when the cookie also sets Secure, Path=/, and no Domain.
+	hostCookiePrefix = "__Host-"
+
+	// OnLimitEvictOldest evicts the user's oldest session to make room for a new one.
+	OnLimitEvictOldest = "evict_oldest"
+	// OnLimitReject refuses the new login while the user is at their session cap.
+	OnLimitReject = "reject"
 )
 
+// ErrSessionLimitReached is returned by Create when a user is at their
+// per-user session cap and the store is configured to reject new logins.
+var ErrSessionLimitReached = errors.New("session limit reached for user")
+
 // Session represents a user session
 type Session struct {
 	ID        string
@@ -26,10 +46,16 @@ type Session struct {
 
 // Store manages user sessions in memory
 type Store struct {
-	mu       sync.RWMutex
-	sessions map[string]*Session
-	stopChan chan struct{}
-	clock    clockwork.Clock
+	mu         sync.RWMutex
+	sessions   map[string]*Session
+	byUsername map[string]map[string]*Session
+	stopChan   chan struct{}
+	clock      clockwork.Clock
+	maxPerUser int           // 0 means unlimited
+	onLimit    string        // OnLimitEvictOldest or OnLimitReject
+	timeout    time.Duration // how long a session remains valid after creation
+	sliding    bool          // whether Touch renews ExpiresAt near expiry
+	skew       time.Duration // tolerance added to ExpiresAt before treating a session as expired
 }
 
 // NewStore creates a new session store with automatic cleanup
@@ -39,10 +65,67 @@ func NewStore() *Store {
 
 // NewStoreWithClock creates a new session store with a custom clock (for testing)
 func NewStoreWithClock(clock clockwork.Clock) *Store {
+	return NewStoreWithLimit(clock, 0, OnLimitEvictOldest)
+}
+
+// NewStoreWithLimit creates a new session store that enforces a per-user
+// session cap. maxPerUser of 0 disables the cap. onLimit selects the policy
+// applied once a user is at the cap: OnLimitEvictOldest or OnLimitReject.
+// Sessions use the default sessionTimeout (24h); use NewStoreWithTimeout to
+// configure it.
+func NewStoreWithLimit(clock clockwork.Clock, maxPerUser int, onLimit string) *Store {
+	return NewStoreWithTimeout(clock, maxPerUser, onLimit, sessionTimeout)
+}
+
+// NewStoreWithTimeout creates a new session store with a per-user session
+// cap, session cap policy, and how long a session remains valid after
+// creation. A timeout <= 0 falls back to sessionTimeout (24h). The
+// background cleanup sweep still runs at cleanupInterval, capped to timeout
+// so it can't run less often than sessions actually expire. Sessions expire
+// at a fixed point in time; use NewStoreWithSliding to renew them on
+// activity instead.
+func NewStoreWithTimeout(clock clockwork.Clock, maxPerUser int, onLimit string, timeout time.Duration) *Store {
+	return NewStoreWithSliding(clock, maxPerUser, onLimit, timeout, false)
+}
+
+// NewStoreWithSliding creates a new session store like NewStoreWithTimeout,
+// additionally controlling whether sessions use sliding expiration. When
+// sliding is true, Touch resets ExpiresAt to now plus timeout whenever a
+// session is used within the last slidingRenewalThreshold fraction of its
+// lifetime, so an active user isn't logged out mid-work; Get never renews.
+// When false,
+// ExpiresAt is fixed at Create time. Sessions get no clock skew tolerance;
+// use NewStoreWithSkew for that.
+func NewStoreWithSliding(clock clockwork.Clock, maxPerUser int, onLimit string, timeout time.Duration, sliding bool) *Store {
+	return NewStoreWithSkew(clock, maxPerUser, onLimit, timeout, sliding, 0)
+}
+
+// NewStoreWithSkew creates a new session store like NewStoreWithSliding,
+// additionally tolerating clock skew in expiry checks: Get, Touch, and the
+// background cleanup all treat a session as expired only once
+// now > ExpiresAt+skew, rather than now > ExpiresAt. This matters once
+// sessions are persisted and loaded across restarts or hosts whose clocks
+// aren't perfectly synchronized, since without it a session created on a
+// host running slightly slow, or checked on a host running slightly fast,
+// could appear already-expired. A negative skew is treated as zero.
+func NewStoreWithSkew(clock clockwork.Clock, maxPerUser int, onLimit string, timeout time.Duration, sliding bool, skew time.Duration) *Store {
+	if timeout <= 0 {
+		timeout = sessionTimeout
+	}
+	if skew < 0 {
+		skew = 0
+	}
+
 	s := &Store{
-		sessions: make(map[string]*Session),
-		stopChan: make(chan struct{}),
-		clock:    clock,
+		sessions:   make(map[string]*Session),
+		byUsername: make(map[string]map[string]*Session),
+		stopChan:   make(chan struct{}),
+		clock:      clock,
+		maxPerUser: maxPerUser,
+		onLimit:    onLimit,
+		timeout:    timeout,
+		sliding:    sliding,
+		skew:       skew,
 	}
 
 	// Start background cleanup goroutine
@@ -51,7 +134,9 @@ func NewStoreWithClock(clock clockwork.Clock) *Store {
 	return s
 }
 
-// Create creates a new session for the given username
+// Create creates a new session for the given username.
+// If the user is at their per-user session cap, the configured policy
+// (OnLimitEvictOldest or OnLimitReject) is applied first.
 func (s *Store) Create(username string) (*Session, error) {
 	sessionID, err := generateSessionID()
 	if err != nil {
@@ -63,41 +148,162 @@ func (s *Store) Create(username string) (*Session, error) {
 		ID:        sessionID,
 		Username:  username,
 		CreatedAt: now,
-		ExpiresAt: now.Add(sessionTimeout),
+		ExpiresAt: now.Add(s.timeout),
 	}
 
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.maxPerUser > 0 {
+		if existing := s.byUsername[username]; len(existing) >= s.maxPerUser {
+			if s.onLimit == OnLimitReject {
+				return nil, ErrSessionLimitReached
+			}
+			s.evictOldestLocked(existing)
+		}
+	}
+
 	s.sessions[sessionID] = session
-	s.mu.Unlock()
+	if s.byUsername[username] == nil {
+		s.byUsername[username] = make(map[string]*Session)
+	}
+	s.byUsername[username][sessionID] = session
+
+	slog.Info("session_created", "session_id", hashSessionID(sessionID), "username", username)
 
 	return session, nil
 }
 
-// Get retrieves a session by ID
-// Returns nil if session doesn't exist or has expired
+// evictOldestLocked removes the oldest session in the given user's set.
+// Callers must hold s.mu.
+func (s *Store) evictOldestLocked(existing map[string]*Session) {
+	var oldestID string
+	var oldestTime time.Time
+	for id, sess := range existing {
+		if oldestID == "" || sess.CreatedAt.Before(oldestTime) {
+			oldestID = id
+			oldestTime = sess.CreatedAt
+		}
+	}
+	if oldestID != "" {
+		delete(s.sessions, oldestID)
+		delete(existing, oldestID)
+	}
+}
+
+// Get retrieves a session by ID.
+// Returns nil if session doesn't exist or has expired. It does not renew
+// sliding expiration - use Touch for that.
 func (s *Store) Get(sessionID string) *Session {
-	s.mu.RLock()
-	session, exists := s.sessions[sessionID]
-	s.mu.RUnlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	session, exists := s.sessions[sessionID]
 	if !exists {
 		return nil
 	}
 
-	// Check expiration
-	if s.clock.Now().After(session.ExpiresAt) {
-		s.Delete(sessionID)
+	if s.isExpired(s.clock.Now(), session.ExpiresAt) {
+		slog.Info("session_expired", "session_id", hashSessionID(sessionID), "username", session.Username)
+		s.deleteLocked(sessionID)
 		return nil
 	}
 
 	return session
 }
 
+// isExpired reports whether expiresAt is more than s.skew in the past
+// relative to now, i.e. whether a session should be treated as expired
+// once clock skew tolerance is taken into account.
+func (s *Store) isExpired(now, expiresAt time.Time) bool {
+	return now.After(expiresAt.Add(s.skew))
+}
+
+// Touch renews sess's expiry if sliding expiration is enabled and it's
+// close enough to expiring. Callers that want sliding expiration should call
+// this after a successful Get, e.g. once per authenticated request. Returns
+// the session's current ExpiresAt and whether it was just renewed. Returns
+// the zero time and false if the session doesn't exist or has already
+// expired.
+func (s *Store) Touch(sessionID string) (expiresAt time.Time, renewed bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, exists := s.sessions[sessionID]
+	if !exists {
+		return time.Time{}, false
+	}
+
+	now := s.clock.Now()
+	if s.isExpired(now, session.ExpiresAt) {
+		s.deleteLocked(sessionID)
+		return time.Time{}, false
+	}
+
+	if !s.sliding {
+		return session.ExpiresAt, false
+	}
+
+	renewed = s.renewIfNearExpiryLocked(session, now)
+	return session.ExpiresAt, renewed
+}
+
+// renewIfNearExpiryLocked bumps sess.ExpiresAt forward by s.timeout if less
+// than slidingRenewalThreshold of its lifetime remains. Callers must hold
+// s.mu.
+func (s *Store) renewIfNearExpiryLocked(sess *Session, now time.Time) bool {
+	remaining := sess.ExpiresAt.Sub(now)
+	if remaining > time.Duration(float64(s.timeout)*slidingRenewalThreshold) {
+		return false
+	}
+	sess.ExpiresAt = now.Add(s.timeout)
+	return true
+}
+
 // Delete removes a session
 func (s *Store) Delete(sessionID string) {
 	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if sess, ok := s.sessions[sessionID]; ok {
+		slog.Info("session_deleted", "session_id", hashSessionID(sessionID), "username", sess.Username)
+	}
+	s.deleteLocked(sessionID)
+}
+
+// deleteLocked removes a session from both indexes. Callers must hold s.mu.
+func (s *Store) deleteLocked(sessionID string) {
+	sess, ok := s.sessions[sessionID]
+	if !ok {
+		return
+	}
 	delete(s.sessions, sessionID)
-	s.mu.Unlock()
+	if byUser, ok := s.byUsername[sess.Username]; ok {
+		delete(byUser, sessionID)
+		if len(byUser) == 0 {
+			delete(s.byUsername, sess.Username)
+		}
+	}
+}
+
+// DeleteByUsername removes every active session belonging to username and
+// returns how many were revoked.
+func (s *Store) DeleteByUsername(username string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	byUser, ok := s.byUsername[username]
+	if !ok {
+		return 0
+	}
+
+	count := len(byUser)
+	for id := range byUser {
+		delete(s.sessions, id)
+	}
+	delete(s.byUsername, username)
+
+	return count
 }
 
 // Count returns the number of active sessions
@@ -107,9 +313,43 @@ func (s *Store) Count() int {
 	return len(s.sessions)
 }
 
+// SessionSummary is a sanitized view of a Session for diagnostics and
+// support bundles. It deliberately omits ID, since that's a bearer
+// credential - anyone holding it could hijack the session.
+type SessionSummary struct {
+	Username  string    `json:"username"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Summaries returns a sanitized snapshot of every active session, for
+// diagnostics and support bundles. Unlike Get, it does not check
+// expiration against the clock - callers get a point-in-time view of
+// what's currently stored, expired entries included, since cleanup runs
+// on its own schedule.
+func (s *Store) Summaries() []SessionSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summaries := make([]SessionSummary, 0, len(s.sessions))
+	for _, sess := range s.sessions {
+		summaries = append(summaries, SessionSummary{
+			Username:  sess.Username,
+			CreatedAt: sess.CreatedAt,
+			ExpiresAt: sess.ExpiresAt,
+		})
+	}
+	return summaries
+}
+
 // cleanupExpiredSessions runs periodically to remove expired sessions
 func (s *Store) cleanupExpiredSessions() {
-	ticker := s.clock.NewTicker(cleanupInterval)
+	interval := cleanupInterval
+	if s.timeout < interval {
+		interval = s.timeout
+	}
+
+	ticker := s.clock.NewTicker(interval)
 	defer ticker.Stop()
 
 	for {
@@ -127,11 +367,18 @@ func (s *Store) cleanup() {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	removed := 0
 	for id, session := range s.sessions {
-		if now.After(session.ExpiresAt) {
-			delete(s.sessions, id)
+		if s.isExpired(now, session.ExpiresAt) {
+			slog.Info("session_expired", "session_id", hashSessionID(id), "username", session.Username)
+			s.deleteLocked(id)
+			removed++
 		}
 	}
+
+	if removed > 0 {
+		slog.Info("sessions_cleaned", "removed", removed, "remaining", len(s.sessions))
+	}
 }
 
 // Close stops the cleanup goroutine
@@ -148,7 +395,23 @@ func generateSessionID() (string, error) {
 	return base64.URLEncoding.EncodeToString(bytes), nil
 }
 
-// GetCookieName returns the name of the session cookie
-func GetCookieName() string {
+// hashSessionID digests a session ID for audit-correlation logging, so log
+// lines can be matched across events without exposing a valid bearer
+// credential to anyone with log access. Truncated to 12 hex characters -
+// plenty to correlate log lines without keeping the full digest around.
+func hashSessionID(sessionID string) string {
+	sum := sha256.Sum256([]byte(sessionID))
+	return hex.EncodeToString(sum[:])[:12]
+}
+
+// GetCookieName returns the name of the session cookie. When hostPrefix is
+// true, it's returned with the __Host- prefix for maximum cookie-hardening;
+// callers must only pass hostPrefix=true when the cookie will also be set
+// with Secure, Path=/, and no Domain, since browsers silently drop __Host-
+// cookies that don't meet those constraints.
+func GetCookieName(hostPrefix bool) string {
+	if hostPrefix {
+		return hostCookiePrefix + sessionCookieName
+	}
 	return sessionCookieName
 }
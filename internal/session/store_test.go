@@ -1,6 +1,8 @@
 package session
 
 import (
+	"bytes"
+	"log/slog"
 	"sync"
 	"testing"
 	"time"
@@ -139,6 +141,32 @@ func TestStore_Delete_NonExistent(t *testing.T) {
 	assert.Equal(t, 0, store.Count())
 }
 
+func TestStore_DeleteByUsername(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+
+	store.Create("alice")
+	store.Create("alice")
+	store.Create("bob")
+
+	revoked := store.DeleteByUsername("alice")
+
+	assert.Equal(t, 2, revoked)
+	assert.Equal(t, 1, store.Count())
+}
+
+func TestStore_DeleteByUsername_UnknownUser(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+
+	store.Create("alice")
+
+	revoked := store.DeleteByUsername("nobody")
+
+	assert.Equal(t, 0, revoked)
+	assert.Equal(t, 1, store.Count())
+}
+
 func TestStore_Count(t *testing.T) {
 	store := NewStore()
 	defer store.Close()
@@ -155,6 +183,27 @@ func TestStore_Count(t *testing.T) {
 	assert.Equal(t, 3, store.Count())
 }
 
+func TestStore_Summaries_ReturnsSanitizedView(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+
+	created, err := store.Create("alice")
+	require.NoError(t, err)
+
+	summaries := store.Summaries()
+	require.Len(t, summaries, 1)
+	assert.Equal(t, "alice", summaries[0].Username)
+	assert.Equal(t, created.CreatedAt, summaries[0].CreatedAt)
+	assert.Equal(t, created.ExpiresAt, summaries[0].ExpiresAt)
+}
+
+func TestStore_Summaries_EmptyWhenNoSessions(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+
+	assert.Empty(t, store.Summaries())
+}
+
 func TestStore_Cleanup(t *testing.T) {
 	fakeClock := clockwork.NewFakeClock()
 	store := NewStoreWithClock(fakeClock)
@@ -187,6 +236,139 @@ func TestStore_Cleanup(t *testing.T) {
 	assert.Equal(t, 2, store.Count())
 }
 
+func TestStore_Cleanup_LogsRemovedAndRemainingCounts(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(fakeClock)
+	defer store.Close()
+
+	store.Create("user1")
+	store.Create("user2")
+
+	fakeClock.Advance(24*time.Hour + time.Second)
+
+	store.cleanup()
+
+	output := buf.String()
+	assert.Contains(t, output, "sessions_cleaned")
+	assert.Contains(t, output, `"removed":2`)
+	assert.Contains(t, output, `"remaining":0`)
+}
+
+func TestStore_Cleanup_NoLogWhenNothingRemoved(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(fakeClock)
+	defer store.Close()
+
+	store.Create("user1")
+
+	store.cleanup()
+
+	assert.NotContains(t, buf.String(), "sessions_cleaned")
+}
+
+func TestStore_Create_LogsSessionCreated(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	store := NewStore()
+	defer store.Close()
+
+	sess, err := store.Create("alice")
+	require.NoError(t, err)
+
+	output := buf.String()
+	assert.Contains(t, output, "session_created")
+	assert.Contains(t, output, `"username":"alice"`)
+	assert.NotContains(t, output, sess.ID)
+}
+
+func TestStore_Get_LogsSessionExpired(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(fakeClock)
+	defer store.Close()
+
+	sess, err := store.Create("alice")
+	require.NoError(t, err)
+
+	buf.Reset()
+	fakeClock.Advance(24*time.Hour + time.Second)
+
+	assert.Nil(t, store.Get(sess.ID))
+
+	output := buf.String()
+	assert.Contains(t, output, "session_expired")
+	assert.Contains(t, output, `"username":"alice"`)
+	assert.NotContains(t, output, sess.ID)
+}
+
+func TestStore_Cleanup_LogsSessionExpiredPerSession(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithClock(fakeClock)
+	defer store.Close()
+
+	sess, err := store.Create("alice")
+	require.NoError(t, err)
+
+	buf.Reset()
+	fakeClock.Advance(24*time.Hour + time.Second)
+	store.cleanup()
+
+	output := buf.String()
+	assert.Contains(t, output, "session_expired")
+	assert.Contains(t, output, `"username":"alice"`)
+	assert.NotContains(t, output, sess.ID)
+}
+
+func TestStore_Delete_LogsSessionDeleted(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	store := NewStore()
+	defer store.Close()
+
+	sess, err := store.Create("alice")
+	require.NoError(t, err)
+
+	buf.Reset()
+	store.Delete(sess.ID)
+
+	output := buf.String()
+	assert.Contains(t, output, "session_deleted")
+	assert.Contains(t, output, `"username":"alice"`)
+	assert.NotContains(t, output, sess.ID)
+}
+
+func TestStore_Delete_UnknownIDDoesNotLog(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	slog.SetDefault(logger)
+
+	store := NewStore()
+	defer store.Close()
+
+	store.Delete("nonexistent-session-id")
+
+	assert.NotContains(t, buf.String(), "session_deleted")
+}
+
 func TestStore_Close(t *testing.T) {
 	store := NewStore()
 
@@ -198,10 +380,15 @@ func TestStore_Close(t *testing.T) {
 }
 
 func TestGetCookieName(t *testing.T) {
-	name := GetCookieName()
+	name := GetCookieName(false)
 	assert.Equal(t, "coinops_session", name)
 }
 
+func TestGetCookieName_HostPrefix(t *testing.T) {
+	name := GetCookieName(true)
+	assert.Equal(t, "__Host-coinops_session", name)
+}
+
 func TestGenerateSessionID(t *testing.T) {
 	id, err := generateSessionID()
 
@@ -292,6 +479,92 @@ func TestStore_ThreadSafety_ConcurrentCreateAndDelete(t *testing.T) {
 	assert.GreaterOrEqual(t, count, 0)
 }
 
+func TestStore_Create_EvictOldestAtLimit(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithLimit(fakeClock, 2, OnLimitEvictOldest)
+	defer store.Close()
+
+	first, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(time.Minute)
+	second, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(time.Minute)
+	third, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	// The oldest session (first) should have been evicted to make room.
+	assert.Nil(t, store.Get(first.ID))
+	assert.NotNil(t, store.Get(second.ID))
+	assert.NotNil(t, store.Get(third.ID))
+	assert.Equal(t, 2, store.Count())
+}
+
+func TestStore_Create_RejectAtLimit(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithLimit(fakeClock, 2, OnLimitReject)
+	defer store.Close()
+
+	first, err := store.Create("testuser")
+	require.NoError(t, err)
+	fakeClock.Advance(time.Minute)
+	second, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	third, err := store.Create("testuser")
+	assert.Nil(t, third)
+	assert.ErrorIs(t, err, ErrSessionLimitReached)
+
+	// Both original sessions remain untouched.
+	assert.NotNil(t, store.Get(first.ID))
+	assert.NotNil(t, store.Get(second.ID))
+	assert.Equal(t, 2, store.Count())
+}
+
+func TestStore_Create_LimitIsPerUser(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithLimit(fakeClock, 1, OnLimitReject)
+	defer store.Close()
+
+	_, err := store.Create("alice")
+	require.NoError(t, err)
+
+	// A different user is unaffected by alice's cap.
+	_, err = store.Create("bob")
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, store.Count())
+}
+
+func TestStore_Create_UnlimitedByDefault(t *testing.T) {
+	store := NewStore()
+	defer store.Close()
+
+	for i := 0; i < 5; i++ {
+		_, err := store.Create("testuser")
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 5, store.Count())
+}
+
+func TestStore_Delete_RemovesFromUsernameIndex(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithLimit(fakeClock, 1, OnLimitReject)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	store.Delete(sess.ID)
+
+	// With the slot freed, a new session for the same user should succeed.
+	_, err = store.Create("testuser")
+	assert.NoError(t, err)
+}
+
 func TestSession_Struct(t *testing.T) {
 	now := time.Now()
 	sess := Session{
@@ -321,3 +594,232 @@ func TestSessionIDLength_Constant(t *testing.T) {
 	// Verify the session ID length is 32 bytes
 	assert.Equal(t, 32, sessionIDLength)
 }
+
+func TestNewStoreWithTimeout_UsesConfiguredExpiry(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithTimeout(fakeClock, 0, OnLimitEvictOldest, time.Hour)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+	assert.Equal(t, fakeClock.Now().Add(time.Hour), sess.ExpiresAt)
+}
+
+func TestNewStoreWithTimeout_ExpiresAtConfiguredBoundary(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithTimeout(fakeClock, 0, OnLimitEvictOldest, time.Hour)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(59 * time.Minute)
+	assert.NotNil(t, store.Get(sess.ID), "session should still be valid just before the configured timeout")
+
+	fakeClock.Advance(2 * time.Minute)
+	assert.Nil(t, store.Get(sess.ID), "session should have expired past the configured timeout")
+}
+
+func TestNewStoreWithTimeout_ZeroFallsBackToDefault(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithTimeout(fakeClock, 0, OnLimitEvictOldest, 0)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+	assert.Equal(t, fakeClock.Now().Add(sessionTimeout), sess.ExpiresAt)
+}
+
+func TestNewStoreWithTimeout_NegativeFallsBackToDefault(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithTimeout(fakeClock, 0, OnLimitEvictOldest, -time.Hour)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+	assert.Equal(t, fakeClock.Now().Add(sessionTimeout), sess.ExpiresAt)
+}
+
+func TestNewStoreWithLimit_UsesDefaultTimeout(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithLimit(fakeClock, 0, OnLimitEvictOldest)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+	assert.Equal(t, fakeClock.Now().Add(sessionTimeout), sess.ExpiresAt)
+}
+
+func TestCleanup_HonorsShortConfiguredTimeout(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithTimeout(fakeClock, 0, OnLimitEvictOldest, 5*time.Minute)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(5*time.Minute + time.Second)
+	store.cleanup()
+
+	assert.Nil(t, store.Get(sess.ID), "session should be swept once past the configured 5-minute timeout")
+}
+
+func TestNewStoreWithSliding_GetNeverRenews(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSliding(fakeClock, 0, OnLimitEvictOldest, time.Hour, true)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+	originalExpiry := sess.ExpiresAt
+
+	// 31 minutes elapsed leaves 29 minutes remaining, within the last 50% of
+	// the hour-long timeout - but Get itself never renews, only Touch does.
+	fakeClock.Advance(31 * time.Minute)
+	got := store.Get(sess.ID)
+	require.NotNil(t, got)
+	assert.Equal(t, originalExpiry, got.ExpiresAt)
+}
+
+func TestStore_Touch_RenewsWhenSlidingAndNearExpiry(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSliding(fakeClock, 0, OnLimitEvictOldest, time.Hour, true)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(31 * time.Minute)
+	expiresAt, renewed := store.Touch(sess.ID)
+	assert.True(t, renewed)
+	assert.Equal(t, fakeClock.Now().Add(time.Hour), expiresAt)
+}
+
+func TestStore_Touch_DoesNotRenewWhenSlidingDisabled(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSliding(fakeClock, 0, OnLimitEvictOldest, time.Hour, false)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(31 * time.Minute)
+	expiresAt, renewed := store.Touch(sess.ID)
+	assert.False(t, renewed)
+	assert.Equal(t, sess.ExpiresAt, expiresAt)
+}
+
+func TestStore_Touch_UnknownSessionReturnsFalse(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSliding(fakeClock, 0, OnLimitEvictOldest, time.Hour, true)
+	defer store.Close()
+
+	expiresAt, renewed := store.Touch("does-not-exist")
+	assert.False(t, renewed)
+	assert.True(t, expiresAt.IsZero())
+}
+
+func TestStore_Touch_ExpiredSessionIsDeletedAndReturnsFalse(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSliding(fakeClock, 0, OnLimitEvictOldest, time.Hour, true)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(2 * time.Hour)
+	expiresAt, renewed := store.Touch(sess.ID)
+	assert.False(t, renewed)
+	assert.True(t, expiresAt.IsZero())
+	assert.Equal(t, 0, store.Count(), "expired session touched should be evicted")
+}
+
+func TestNewStoreWithTimeout_SlidingDefaultsToDisabled(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithTimeout(fakeClock, 0, OnLimitEvictOldest, time.Hour)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+	originalExpiry := sess.ExpiresAt
+
+	fakeClock.Advance(31 * time.Minute)
+	got := store.Get(sess.ID)
+	require.NotNil(t, got)
+	assert.Equal(t, originalExpiry, got.ExpiresAt, "NewStoreWithTimeout should preserve fixed-expiry behavior")
+}
+
+func TestNewStoreWithSkew_GetHonorsToleranceJustBeyondExpiry(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSkew(fakeClock, 0, OnLimitEvictOldest, time.Hour, false, 5*time.Second)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	// 3 seconds past ExpiresAt, within the 5-second skew tolerance.
+	fakeClock.Advance(time.Hour + 3*time.Second)
+
+	got := store.Get(sess.ID)
+	require.NotNil(t, got, "a session within the skew tolerance should still be considered valid")
+	assert.Equal(t, 1, store.Count())
+}
+
+func TestNewStoreWithSkew_GetExpiresOnceBeyondTolerance(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSkew(fakeClock, 0, OnLimitEvictOldest, time.Hour, false, 5*time.Second)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	// 10 seconds past ExpiresAt, beyond the 5-second skew tolerance.
+	fakeClock.Advance(time.Hour + 10*time.Second)
+
+	got := store.Get(sess.ID)
+	assert.Nil(t, got, "a session beyond the skew tolerance should expire")
+	assert.Equal(t, 0, store.Count())
+}
+
+func TestNewStoreWithSkew_TouchHonorsTolerance(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSkew(fakeClock, 0, OnLimitEvictOldest, time.Hour, false, 5*time.Second)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(time.Hour + 3*time.Second)
+
+	expiresAt, renewed := store.Touch(sess.ID)
+	assert.False(t, renewed, "sliding is disabled, so Touch shouldn't renew")
+	assert.Equal(t, sess.ExpiresAt, expiresAt)
+}
+
+func TestNewStoreWithSkew_NegativeSkewTreatedAsZero(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSkew(fakeClock, 0, OnLimitEvictOldest, time.Hour, false, -5*time.Second)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(time.Hour + time.Second)
+
+	got := store.Get(sess.ID)
+	assert.Nil(t, got, "a negative skew should behave like zero tolerance")
+}
+
+func TestNewStoreWithSliding_DefaultsToZeroSkew(t *testing.T) {
+	fakeClock := clockwork.NewFakeClock()
+	store := NewStoreWithSliding(fakeClock, 0, OnLimitEvictOldest, time.Hour, false)
+	defer store.Close()
+
+	sess, err := store.Create("testuser")
+	require.NoError(t, err)
+
+	fakeClock.Advance(time.Hour + time.Second)
+
+	got := store.Get(sess.ID)
+	assert.Nil(t, got, "NewStoreWithSliding should apply no skew tolerance by default")
+}